@@ -17,8 +17,10 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 	"github.com/umeh-promise/social/docs" // This is required to generate the swagger docs
 	"github.com/umeh-promise/social/internal/auth"
+	"github.com/umeh-promise/social/internal/blob"
 	"github.com/umeh-promise/social/internal/env"
 	"github.com/umeh-promise/social/internal/mailer"
+	"github.com/umeh-promise/social/internal/ranking"
 	"github.com/umeh-promise/social/internal/ratelimiter"
 	"github.com/umeh-promise/social/internal/store"
 	"github.com/umeh-promise/social/internal/store/cache"
@@ -33,6 +35,18 @@ type application struct {
 	mailer        mailer.Client
 	authenticator auth.Authenticator
 	rateLimiter   ratelimiter.Limiter
+	// twoFactorLimiter throttles second factor attempts per account
+	twoFactorLimiter ratelimiter.Limiter
+	// identityProviders are the external logins, keyed by provider name
+	identityProviders map[string]auth.IdentityProvider
+	// activationLimiter throttles activation emails per address
+	activationLimiter ratelimiter.Limiter
+	// blobStore holds the uploaded media
+	blobStore blob.Store
+	// cursors signs the pagination cursors handed to clients
+	cursors *store.CursorCodec
+	// ranker orders the ranked feed
+	ranker ranking.Ranker
 }
 
 type config struct {
@@ -45,6 +59,48 @@ type config struct {
 	auth        authConfig
 	cache       cacheConfig
 	rateLimiter ratelimiter.Config
+	posts       postsConfig
+	media       mediaConfig
+	pagination  paginationConfig
+	feed        feedConfig
+}
+
+type mediaConfig struct {
+	// backend is either "local", storing blobs below dir, or "s3"
+	backend        string
+	dir            string
+	s3             blob.S3Config
+	maxUploadSize  int64
+	maxAttachments int
+	thumbnailSize  int
+}
+
+type feedConfig struct {
+	// maxFanOutFollowers is the number of followers past which the posts of a
+	// user are not pushed to the timelines of their followers, but merged in
+	// when the timelines are read
+	maxFanOutFollowers int
+	// rankingHalfLife is the age at which the recency score of a post in the
+	// ranked feed halves
+	rankingHalfLife time.Duration
+	// rankingWindow is how far back the ranked feed looks for posts, and
+	// rankingCandidates the most posts it ranks
+	rankingWindow     time.Duration
+	rankingCandidates int
+	// affinityWindow is how far back interactions with an author count
+	// towards the affinity with them
+	affinityWindow time.Duration
+}
+
+type paginationConfig struct {
+	// cursorSecret signs the cursors, a random one is used when empty so
+	// cursors do not survive restarts
+	cursorSecret string
+}
+
+type postsConfig struct {
+	publishInterval time.Duration
+	publishBatch    int
 }
 
 type cacheConfig struct {
@@ -57,12 +113,30 @@ type cacheConfig struct {
 type authConfig struct {
 	basic basicConfig
 	token tokenConfig
+	oauth oauthConfig
+}
+
+type oauthConfig struct {
+	stateExp  time.Duration
+	providers []oidcProviderConfig
+}
+
+type oidcProviderConfig struct {
+	name         string
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
 }
 
 type tokenConfig struct {
-	secret string
-	exp    time.Duration
-	issuer string
+	keysDir         string
+	keyPublishDelay time.Duration
+	keyReload       time.Duration
+	exp             time.Duration
+	challengeExp    time.Duration
+	refreshExp      time.Duration
+	issuer          string
 }
 
 type basicConfig struct {
@@ -79,8 +153,12 @@ type dbConfig struct {
 
 type mailConfig struct {
 	exp       time.Duration
+	resetExp  time.Duration
 	fromEmail string
 	sendGrid  sendGridConfig
+	// accounts not activated within inactiveGrace are purged every sweepInterval
+	inactiveGrace time.Duration
+	sweepInterval time.Duration
 }
 
 type sendGridConfig struct {
@@ -97,9 +175,9 @@ func (app *application) mount() *chi.Mux {
 	router.Use(middleware.Recoverer)
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "https://localhost:4000")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -110,6 +188,8 @@ func (app *application) mount() *chi.Mux {
 	// processing should be stopped.
 	router.Use(middleware.Timeout(60 * time.Second))
 
+	router.Get("/.well-known/jwks.json", app.jwksHandler)
+
 	router.Route("/v1", func(router chi.Router) {
 		// router.With(app.BasicAuthMiddleware()).Get("/health", app.healthCheckHandler)
 		router.Get("/health", app.healthCheckHandler)
@@ -119,39 +199,149 @@ func (app *application) mount() *chi.Mux {
 		router.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
 
 		router.Route("/posts", func(router chi.Router) {
-			router.Use(app.AuthTokenMiddleware)
-			router.Post("/", app.createPostHandler)
+			router.Use(app.AuthMiddleware)
+			router.With(app.RequireScope(store.ScopePostsWrite)).Post("/", app.createPostHandler)
 
 			router.Route("/{id}", func(router chi.Router) {
 				router.Use(app.postMiddlewareHandler)
-				router.Get("/", app.getPostHandler)
-
-				router.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
-				router.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
+				router.With(app.RequireScope(store.ScopePostsRead)).Get("/", app.getPostHandler)
+
+				router.Group(func(router chi.Router) {
+					router.Use(app.RequireScope(store.ScopePostsWrite))
+					router.Patch("/", app.checkPostOwnership(store.PermissionPostUpdateAny, app.checkPostPrecondition(app.updatePostHandler)))
+					router.Delete("/", app.checkPostOwnership(store.PermissionPostDeleteAny, app.checkPostPrecondition(app.deletePostHandler)))
+				})
+
+				router.Route("/revisions", func(router chi.Router) {
+					router.With(app.RequireScope(store.ScopePostsRead)).Get("/", app.listRevisionsHandler)
+					router.With(
+						app.RequireScope(store.ScopePostsWrite),
+						app.RequirePermission(store.PermissionPostRestore),
+					).Post("/{version}/restore", app.checkPostPrecondition(app.restoreRevisionHandler))
+				})
+
+				router.Route("/attachments", func(router chi.Router) {
+					router.Use(app.RequireScope(store.ScopePostsWrite))
+					router.Post("/", app.uploadAttachmentHandler)
+					router.Delete("/{attachmentID}", app.checkPostOwnership(store.PermissionPostUpdateAny, app.deleteAttachmentHandler))
+				})
+
+				router.With(app.RequireScope(store.ScopePostsWrite)).Post("/poll/votes", app.votePollHandler)
+
+				router.Route("/repost", func(router chi.Router) {
+					router.Use(app.RequireScope(store.ScopePostsWrite))
+					router.Post("/", app.repostHandler)
+					router.Delete("/", app.deleteRepostHandler)
+				})
+
+				router.Route("/reactions/{kind}", func(router chi.Router) {
+					router.Use(app.RequireScope(store.ScopePostsWrite))
+					router.Put("/", app.setPostReactionHandler)
+					router.Delete("/", app.removePostReactionHandler)
+				})
+
+				router.Route("/comments", func(router chi.Router) {
+					router.With(app.RequireScope(store.ScopePostsRead)).Get("/", app.listCommentsHandler)
+					router.With(app.RequireScope(store.ScopePostsWrite)).Post("/", app.createCommentHandler)
+				})
 			})
 		})
 
+		router.With(app.AuthMiddleware, app.RequireScope(store.ScopePostsRead)).Get("/tags/{tag}/posts", app.listHashtagPostsHandler)
+
+		// media of posts anyone may see can be embedded without credentials
+		router.With(app.OptionalAuthMiddleware, app.RequireScope(store.ScopePostsRead)).Get("/media/*", app.getMediaHandler)
+
+		router.Route("/comments/{commentID}", func(router chi.Router) {
+			router.Use(app.AuthMiddleware, app.commentMiddlewareHandler, app.RequireScope(store.ScopePostsWrite))
+			router.Patch("/", app.checkCommentOwnership(store.PermissionCommentUpdateAny, app.updateCommentHandler))
+			router.Delete("/", app.checkCommentOwnership(store.PermissionCommentDeleteAny, app.deleteCommentHandler))
+			router.Put("/reactions/{kind}", app.setCommentReactionHandler)
+			router.Delete("/reactions/{kind}", app.removeCommentReactionHandler)
+		})
+
 		router.Route("/users", func(router chi.Router) {
 			router.Put("/activate/{token}", app.activateHandler)
 
-			router.Route("/{id}", func(router chi.Router) {
+			router.Route("/me", func(router chi.Router) {
+				// account data and credentials can only be managed with a session token
 				router.Use(app.AuthTokenMiddleware)
+
+				router.Route("/sessions", func(router chi.Router) {
+					router.Get("/", app.listSessionsHandler)
+					router.Delete("/{sessionID}", app.deleteSessionHandler)
+				})
+
+				router.Route("/2fa", func(router chi.Router) {
+					router.Post("/enroll", app.enrollTwoFactorHandler)
+					router.Post("/verify", app.verifyTwoFactorHandler)
+					router.Delete("/", app.disableTwoFactorHandler)
+				})
+
+				router.Get("/drafts", app.listDraftsHandler)
+				router.Get("/mentions", app.listMentionsHandler)
+
+				router.Route("/bookmarks", func(router chi.Router) {
+					router.Get("/", app.listBookmarkCollectionsHandler)
+					router.Post("/", app.createBookmarkCollectionHandler)
+
+					router.Route("/{collectionID}", func(router chi.Router) {
+						router.Delete("/", app.deleteBookmarkCollectionHandler)
+						router.Get("/posts", app.listBookmarksHandler)
+						router.Put("/posts/{postID}", app.addBookmarkHandler)
+						router.Delete("/posts/{postID}", app.removeBookmarkHandler)
+					})
+				})
+
+				router.Route("/api-keys", func(router chi.Router) {
+					router.Use(app.TwoFactorEnrollmentMiddleware)
+					router.Get("/", app.listAPIKeysHandler)
+					router.Post("/", app.createAPIKeyHandler)
+					router.Delete("/{keyID}", app.revokeAPIKeyHandler)
+				})
+			})
+
+			router.Route("/{id}", func(router chi.Router) {
+				router.Use(app.AuthMiddleware)
 				// router.Use(app.userMiddlewareHandler)
 
-				router.Get("/", app.getUserHandler)
-				router.Put("/follow", app.followUserHandler)
-				router.Put("/unfollow", app.unfollowUserHandler)
+				router.With(app.RequireScope(store.ScopeUsersRead)).Get("/", app.getUserHandler)
+				router.With(app.RequireScope(store.ScopeUsersRead)).Get("/followers", app.listFollowersHandler)
+				router.With(app.RequireScope(store.ScopeUsersRead)).Get("/following", app.listFollowingHandler)
+				router.With(app.RequireScope(store.ScopeUsersWrite)).Put("/follow", app.followUserHandler)
+				router.With(app.RequireScope(store.ScopeUsersWrite)).Put("/unfollow", app.unfollowUserHandler)
 			})
 
 			router.Group(func(r chi.Router) {
-				router.With(app.AuthTokenMiddleware).Get("/feed", app.getUserFeedHandler)
+				router.With(app.AuthMiddleware, app.RequireScope(store.ScopeFeedRead)).Get("/feed", app.getUserFeedHandler)
 			})
 		})
 
+		router.Route("/admin", func(router chi.Router) {
+			router.Use(app.AuthTokenMiddleware, app.TwoFactorEnrollmentMiddleware)
+
+			router.Route("/roles", func(router chi.Router) {
+				router.With(app.RequirePermission(store.PermissionRoleAssign)).Get("/", app.listRolesHandler)
+				router.With(app.RequirePermission(store.PermissionRoleCreate)).Post("/", app.createRoleHandler)
+			})
+			router.With(app.RequirePermission(store.PermissionRoleAssign)).Put("/users/{id}/role", app.assignRoleHandler)
+		})
+
 		// Public routes
 		router.Route("/auth", func(router chi.Router) {
 			router.Post("/user", app.registerUserHandler)
 			router.Post("/token", app.createTokenHandler)
+			router.Post("/token/2fa", app.createTwoFactorTokenHandler)
+			router.Post("/refresh", app.refreshTokenHandler)
+			router.With(app.AuthTokenMiddleware).Post("/logout", app.logoutHandler)
+			router.Post("/password/forgot", app.forgotPasswordHandler)
+			router.Post("/password/reset", app.resetPasswordHandler)
+			router.Post("/activation/resend", app.resendActivationHandler)
+
+			router.Route("/oauth/{provider}", func(router chi.Router) {
+				router.Get("/", app.startOAuthHandler)
+				router.Get("/callback", app.oauthCallbackHandler)
+			})
 		})
 	})
 
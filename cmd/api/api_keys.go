@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+type apiKeyKey string
+
+const apiKeyCtx apiKeyKey = "apiKey"
+
+// apiKeyPrefix marks our keys so they are easy to spot in logs and by secret
+// scanners.
+const apiKeyPrefix = "sk_"
+
+type CreateAPIKeyPayload struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=posts:read posts:write feed:read users:read users:write"`
+}
+
+type APIKeyWithSecret struct {
+	*store.APIKey
+	Key string `json:"key"`
+}
+
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateAPIKeyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	plainKey := apiKeyPrefix + secret
+
+	key := &store.APIKey{
+		UserID: user.ID,
+		Name:   payload.Name,
+		Prefix: plainKey[:len(apiKeyPrefix)+8],
+		Scopes: payload.Scopes,
+	}
+
+	if err := app.store.APIKeys.Create(r.Context(), key, plainKey); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// the plain key is only ever shown in this response
+	if err := app.jsonResponse(w, http.StatusCreated, APIKeyWithSecret{APIKey: key, Key: plainKey}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	keys, err := app.store.APIKeys.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, keys); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "keyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.APIKeys.Revoke(r.Context(), user.ID, id); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AuthMiddleware accepts either a session token (`Bearer <jwt>`) or a
+// personal API key (`ApiKey sk_...`).
+func (app *application) AuthMiddleware(next http.Handler) http.Handler {
+	next = app.TwoFactorEnrollmentMiddleware(next)
+	tokenAuth := app.AuthTokenMiddleware(next)
+	keyAuth := app.APIKeyMiddleware(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Authorization"), "ApiKey ") {
+			keyAuth.ServeHTTP(w, r)
+			return
+		}
+
+		tokenAuth.ServeHTTP(w, r)
+	})
+}
+
+// OptionalAuthMiddleware is AuthMiddleware for routes also open to anonymous
+// requests, which come without an Authorization header and without a user in
+// the context.
+func (app *application) OptionalAuthMiddleware(next http.Handler) http.Handler {
+	auth := app.AuthMiddleware(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		auth.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) APIKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// read the auth header
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			app.unathorizedErrorResponse(w, r, fmt.Errorf("authorization header is missing"))
+			return
+		}
+
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "ApiKey" || !strings.HasPrefix(parts[1], apiKeyPrefix) {
+			app.unathorizedErrorResponse(w, r, fmt.Errorf("authorization header is malformed"))
+			return
+		}
+
+		ctx := r.Context()
+		key, err := app.store.APIKeys.GetByKey(ctx, parts[1])
+		if err != nil {
+			switch err {
+			case store.ErrorNotFound:
+				app.unathorizedErrorResponse(w, r, fmt.Errorf("invalid api key"))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		user, err := app.getUser(ctx, key.UserID)
+		if err != nil {
+			app.unathorizedErrorResponse(w, r, err)
+			return
+		}
+
+		if err := app.store.APIKeys.Touch(ctx, key.ID); err != nil {
+			app.logger.Errorw("error updating api key last used", "error", err)
+		}
+
+		ctx = context.WithValue(ctx, userCtx, user)
+		ctx = context.WithValue(ctx, apiKeyCtx, key)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScope restricts API key requests to keys granted the scope. Session
+// tokens are not scoped and always pass.
+func (app *application) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := r.Context().Value(apiKeyCtx).(*store.APIKey)
+			if ok && !key.HasScope(scope) {
+				app.forbiddenResponseError(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.APIKeys = &store.MockAPIKeyStore{Keys: map[string]*store.APIKey{
+		"sk_reader": {ID: 1, UserID: 1, Scopes: []string{store.ScopeUsersRead}},
+		"sk_writer": {ID: 2, UserID: 1, Scopes: []string{store.ScopeUsersWrite}},
+	}}
+	mux := app.mount()
+
+	tests := []struct {
+		name   string
+		header string
+		method string
+		path   string
+		code   int
+	}{
+		{"key with the scope", "ApiKey sk_reader", http.MethodGet, "/v1/users/2", http.StatusOK},
+		{"key without the scope", "ApiKey sk_writer", http.MethodGet, "/v1/users/2", http.StatusForbidden},
+		{"key without the write scope", "ApiKey sk_reader", http.MethodPut, "/v1/users/2/follow", http.StatusForbidden},
+		{"unknown key", "ApiKey sk_unknown", http.MethodGet, "/v1/users/2", http.StatusUnauthorized},
+		{"key without the prefix", "ApiKey reader", http.MethodGet, "/v1/users/2", http.StatusUnauthorized},
+		{"malformed header", "ApiKey", http.MethodGet, "/v1/users/2", http.StatusUnauthorized},
+		{"key managing the account", "ApiKey sk_reader", http.MethodGet, "/v1/users/me/sessions", http.StatusUnauthorized},
+		{"key managing keys", "ApiKey sk_writer", http.MethodGet, "/v1/users/me/api-keys", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", tt.header)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not scope session tokens", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/2", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.APIKeys = &store.MockAPIKeyStore{Keys: map[string]*store.APIKey{
+		"sk_own":   {ID: 1, UserID: 1, Scopes: []string{store.ScopePostsRead}},
+		"sk_other": {ID: 2, UserID: 2, Scopes: []string{store.ScopePostsRead}},
+	}}
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"creating a key", http.MethodPost, "/v1/users/me/api-keys", `{"name":"bot","scopes":["posts:write"]}`, http.StatusCreated},
+		{"creating a key without scopes", http.MethodPost, "/v1/users/me/api-keys", `{"name":"bot","scopes":[]}`, http.StatusBadRequest},
+		{"creating a key with an unknown scope", http.MethodPost, "/v1/users/me/api-keys", `{"name":"bot","scopes":["admin"]}`, http.StatusBadRequest},
+		{"listing keys", http.MethodGet, "/v1/users/me/api-keys", "", http.StatusOK},
+		{"revoking an own key", http.MethodDelete, "/v1/users/me/api-keys/1", "", http.StatusNoContent},
+		{"revoking a key of another user", http.MethodDelete, "/v1/users/me/api-keys/2", "", http.StatusNotFound},
+		{"invalid key", http.MethodDelete, "/v1/users/me/api-keys/first", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+
+			req, err := http.NewRequest(tt.method, tt.path, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+
+			if tt.code == http.StatusCreated && !strings.Contains(rr.Body.String(), `"key":"sk_`) {
+				t.Errorf("expected the new key to be shown, got %s", rr.Body.String())
+			}
+		})
+	}
+}
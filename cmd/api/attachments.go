@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/umeh-promise/social/internal/blob"
+	"github.com/umeh-promise/social/internal/imaging"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+var imageExtensions = map[string]string{
+	imaging.TypeJPEG: ".jpg",
+	imaging.TypePNG:  ".png",
+	imaging.TypeGIF:  ".gif",
+}
+
+// uploadAttachmentHandler adds an image to one of the user's posts. It takes a
+// multipart form with the image in the "file" field.
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenResponseError(w, r)
+		return
+	}
+
+	if post.Kind == store.PostKindRepost {
+		app.badRequestResponse(w, r, errors.New("reposts cannot have attachments"))
+		return
+	}
+
+	maxSize := app.config.media.maxUploadSize
+
+	// leave some room for the rest of the form
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			app.payloadTooLargeResponse(w, r, fmt.Errorf("images are limited to %d bytes", maxSize))
+			return
+		}
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if int64(len(data)) > maxSize {
+		app.payloadTooLargeResponse(w, r, fmt.Errorf("images are limited to %d bytes", maxSize))
+		return
+	}
+
+	// the declared content type is ignored, the image is sniffed and decoded
+	original, thumbnail, err := imaging.Process(data, app.config.media.thumbnailSize)
+	if err != nil {
+		switch err {
+		case imaging.ErrUnsupportedType:
+			app.unsupportedMediaTypeResponse(w, r, errors.New("only JPEG, PNG and GIF images are supported"))
+		case imaging.ErrTooLarge:
+			app.payloadTooLargeResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	name := fmt.Sprintf("posts/%d/%s", post.ID, uuid.New().String())
+	attachment := &store.Attachment{
+		PostID:       post.ID,
+		Key:          name + imageExtensions[original.ContentType],
+		ThumbnailKey: name + "_thumb" + imageExtensions[thumbnail.ContentType],
+		ContentType:  original.ContentType,
+		Size:         int64(len(original.Data)),
+		Width:        original.Width,
+		Height:       original.Height,
+	}
+
+	ctx := r.Context()
+
+	if err := app.putImage(ctx, attachment.Key, original); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.putImage(ctx, attachment.ThumbnailKey, thumbnail); err != nil {
+		app.deleteBlobs(attachment.Key)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Attachments.Create(ctx, attachment, app.config.media.maxAttachments); err != nil {
+		app.deleteBlobs(attachment.Key, attachment.ThumbnailKey)
+
+		switch err {
+		case store.ErrorAttachmentLimit:
+			app.badRequestResponse(w, r, fmt.Errorf("posts are limited to %d attachments", app.config.media.maxAttachments))
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, attachment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "attachmentID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+
+	attachment, err := app.store.Attachments.Delete(r.Context(), post.ID, id)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.deleteBlobs(attachment.Key, attachment.ThumbnailKey)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mediaMaxAge is how long media may be cached. Keys are never reused, but
+// caches have to come back to find out when the post of an attachment is
+// deleted or narrows its visibility.
+const mediaMaxAge = time.Hour
+
+// getMediaHandler serves the blobs of attachments to those who may see their
+// post. Media of posts that are not public is only served to authenticated
+// viewers, and only cached by their own client.
+func (app *application) getMediaHandler(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+	ctx := r.Context()
+
+	post, err := app.store.Attachments.GetPost(ctx, key)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// anonymous requests see what everyone sees
+	user, ok := ctx.Value(userCtx).(*store.User)
+	if !ok {
+		user = &store.User{}
+	}
+
+	visible, err := app.canSeePost(ctx, post, user)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !visible {
+		app.notFoundResponse(w, r, store.ErrorNotFound)
+		return
+	}
+
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(mediaMaxAge.Seconds()))
+	if !post.VisibleTo(0, false) {
+		cacheControl = fmt.Sprintf("private, max-age=%d", int(mediaMaxAge.Seconds()))
+		w.Header().Set("Vary", "Authorization")
+	}
+
+	body, contentType, err := app.blobStore.Get(ctx, key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound), errors.Is(err, blob.ErrInvalidKey):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if _, err := io.Copy(w, body); err != nil {
+		app.logger.Warnw("error serving media", "key", key, "error", err)
+	}
+}
+
+func (app *application) putImage(ctx context.Context, key string, img *imaging.Image) error {
+	return app.blobStore.Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType)
+}
+
+// deleteBlobs removes blobs in the background once the rows pointing to them
+// are gone. Failures only leave unreachable blobs behind, so they are logged.
+func (app *application) deleteBlobs(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for _, key := range keys {
+			if err := app.blobStore.Delete(ctx, key); err != nil {
+				app.logger.Errorw("error deleting blob", "key", key, "error", err)
+			}
+		}
+	}()
+}
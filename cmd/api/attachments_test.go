@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/umeh-promise/social/internal/blob"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestGetMedia(t *testing.T) {
+	app := newTestApplication(t)
+
+	blobStore, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.blobStore = blobStore
+
+	// user 1, the user of the test token, follows user 3
+	app.store.Attachments = &store.MockAttachmentStore{Posts: map[string]*store.Post{
+		"posts/1/image.png":       {ID: 1, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPublic},
+		"posts/1/image_thumb.png": {ID: 1, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPublic},
+		"posts/2/image.png":       {ID: 2, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPrivate},
+		"posts/3/image.png":       {ID: 3, UserID: 1, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPrivate},
+		"posts/4/image.png":       {ID: 4, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityFollowers},
+		"posts/5/image.png":       {ID: 5, UserID: 3, Status: store.PostStatusPublished, Visibility: store.PostVisibilityFollowers},
+		"posts/6/image.png":       {ID: 6, UserID: 2, Status: store.PostStatusDraft, Visibility: store.PostVisibilityPublic},
+	}}
+	app.store.Followers = &store.MockFollowerStore{Following: map[int64][]int64{1: {3}}}
+	mux := app.mount()
+
+	for _, key := range []string{
+		"posts/1/image.png", "posts/1/image_thumb.png", "posts/2/image.png", "posts/3/image.png",
+		"posts/4/image.png", "posts/5/image.png", "posts/6/image.png", "posts/7/image.png",
+	} {
+		if err := blobStore.Put(context.Background(), key, strings.NewReader("png"), 3, "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		authorized   bool
+		code         int
+		cacheControl string
+	}{
+		{"public post without authentication", "/v1/media/posts/1/image.png", false, http.StatusOK, "public, max-age=3600"},
+		{"thumbnail of a public post", "/v1/media/posts/1/image_thumb.png", false, http.StatusOK, "public, max-age=3600"},
+		{"public post", "/v1/media/posts/1/image.png", true, http.StatusOK, "public, max-age=3600"},
+		{"private post without authentication", "/v1/media/posts/2/image.png", false, http.StatusNotFound, ""},
+		{"private post of another user", "/v1/media/posts/2/image.png", true, http.StatusNotFound, ""},
+		{"own private post", "/v1/media/posts/3/image.png", true, http.StatusOK, "private, max-age=3600"},
+		{"followers only post, not following", "/v1/media/posts/4/image.png", true, http.StatusNotFound, ""},
+		{"followers only post, following", "/v1/media/posts/5/image.png", true, http.StatusOK, "private, max-age=3600"},
+		{"followers only post without authentication", "/v1/media/posts/5/image.png", false, http.StatusNotFound, ""},
+		{"draft of another user", "/v1/media/posts/6/image.png", true, http.StatusNotFound, ""},
+		{"blob of no attachment", "/v1/media/posts/7/image.png", false, http.StatusNotFound, ""},
+		{"outside the store", "/v1/media/posts/%2E%2E/%2E%2E/secret", false, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.authorized {
+				req.Header.Set("Authorization", "Bearer "+testToken)
+			}
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+
+			if tt.code != http.StatusOK {
+				return
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "image/png" {
+				t.Errorf("expected image/png, got %s", contentType)
+			}
+			if cacheControl := rr.Header().Get("Cache-Control"); cacheControl != tt.cacheControl {
+				t.Errorf("expected Cache-Control %q, got %q", tt.cacheControl, cacheControl)
+			}
+		})
+	}
+
+	t.Run("should reject invalid credentials", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/media/posts/1/image.png", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer invalid")
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+}
@@ -1,8 +1,8 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"time"
@@ -51,12 +51,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	plainToken := uuid.New().String()
 
-	// store token
-	hash := sha256.Sum256([]byte(plainToken))
-	hashToken := hex.EncodeToString(hash[:])
-
-	// store the user
-	err := app.store.Users.CreateAndInvite(ctx, user, hashToken, app.config.mail.exp)
+	// store the user, the store keeps a hash of the token
+	err := app.store.Users.CreateAndInvite(ctx, user, plainToken, app.config.mail.exp)
 	if err != nil {
 		switch err {
 		case store.ErrorDuplicateEmail:
@@ -125,6 +121,95 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		switch err {
 		case store.ErrorNotFound:
+			// still run the password comparison below so an unknown email
+			// takes as long to reject as a wrong password
+			user = &store.User{}
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	// verify the password against the stored hash
+	if err := user.Password.Compare(payload.Password); err != nil {
+		switch err {
+		case store.ErrorInvalidCredentials:
+			app.unathorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// only activated accounts can log in
+	if !user.IsActive {
+		app.unathorizedErrorResponse(w, r, store.ErrorAccountNotActivated)
+		return
+	}
+
+	app.completeLogin(w, r, user)
+}
+
+// completeLogin answers a request whose first factor has been verified: users
+// with two-factor enabled get a challenge, everyone else a new session.
+func (app *application) completeLogin(w http.ResponseWriter, r *http.Request, user *store.User) {
+	if user.TwoFactorEnabled {
+		challenge, err := app.newTwoFactorChallenge(user)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusOK, challenge); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// start a new session -> access + refresh token
+	tokens, err := app.createSession(r, user)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// send it to the client
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// rotate the refresh token, the old one can never be used again
+	session, err := app.store.Sessions.Refresh(r.Context(), payload.RefreshToken, refreshToken, app.config.auth.token.refreshExp)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.unathorizedErrorResponse(w, r, fmt.Errorf("invalid or expired refresh token"))
+		case store.ErrorTokenReused:
+			app.logger.Warnw("refresh token reuse detected, session revoked", "path", r.URL.Path)
 			app.unathorizedErrorResponse(w, r, err)
 		default:
 			app.internalServerError(w, r, err)
@@ -132,9 +217,62 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	tokens, err := app.newTokenPair(session, refreshToken)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	session := getSessionFromCtx(r)
+
+	if err := app.store.Sessions.Revoke(r.Context(), session.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// createSession starts a new login session for the user on the device making
+// the request and issues its first access/refresh token pair.
+func (app *application) createSession(r *http.Request, user *store.User) (*TokenPair, error) {
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &store.Session{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		UserAgent: clientUserAgent(r),
+		IP:        clientIP(r),
+	}
+	if err := app.store.Sessions.Create(r.Context(), session, refreshToken, app.config.auth.token.refreshExp); err != nil {
+		return nil, err
+	}
+
+	return app.newTokenPair(session, refreshToken)
+}
+
+func (app *application) newTokenPair(session *store.Session, refreshToken string) (*TokenPair, error) {
 	// generate a token -> add claims
 	claims := jwt.MapClaims{
-		"sub": user.ID,
+		"sub": session.UserID,
+		"sid": session.ID,
+		"typ": accessTokenType,
 		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
 		"iat": time.Now().Unix(),
 		"nbf": time.Now().Unix(),
@@ -143,12 +281,42 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 	}
 	token, err := app.authenticator.GenerateToken(claims)
 	if err != nil {
-		app.internalServerError(w, r, err)
-		return
+		return nil, err
 	}
 
-	// send it to the client
-	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+	return &TokenPair{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(app.config.auth.token.exp.Seconds()),
+	}, nil
+}
+
+// generateOpaqueToken returns a random url-safe token with 256 bits of entropy.
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type sessionKey string
+
+const sessionCtx sessionKey = "session"
+
+func getSessionFromCtx(r *http.Request) *store.Session {
+	session := r.Context().Value(sessionCtx).(*store.Session)
+
+	return session
+}
+
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	// verifiers may cache the key set, new keys are published well before
+	// they start signing
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if err := writeJSON(w, http.StatusOK, app.authenticator.JWKS()); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
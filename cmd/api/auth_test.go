@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateToken(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	t.Run("should reject unknown credentials", func(t *testing.T) {
+		body := strings.NewReader(`{"email":"nobody@example.com","password":"secret123"}`)
+		req, err := http.NewRequest(http.MethodPost, "/v1/auth/token", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should reject malformed payloads", func(t *testing.T) {
+		body := strings.NewReader(`{"email":"not-an-email","password":"secret123"}`)
+		req, err := http.NewRequest(http.MethodPost, "/v1/auth/token", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestRefreshToken(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	t.Run("should reject unknown refresh tokens", func(t *testing.T) {
+		body := strings.NewReader(`{"refresh_token":"unknown"}`)
+		req, err := http.NewRequest(http.MethodPost, "/v1/auth/refresh", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not allow unauthenticated requests", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/auth/logout", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should revoke the current session", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/auth/logout", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+}
+
+func TestOAuthLogin(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	t.Run("should not find unknown identity providers", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/auth/oauth/unknown", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestResendActivation(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	resend := func(t *testing.T, body string) int {
+		req, err := http.NewRequest(http.MethodPost, "/v1/auth/activation/resend", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should not reveal unknown emails", func(t *testing.T) {
+		checkResponseCode(t, http.StatusAccepted, resend(t, `{"email":"nobody@example.com"}`))
+	})
+
+	t.Run("should reject malformed payloads", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, resend(t, `{"email":"not-an-email"}`))
+	})
+
+	t.Run("should throttle resends per email", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			checkResponseCode(t, http.StatusAccepted, resend(t, `{"email":"throttled@example.com"}`))
+		}
+		checkResponseCode(t, http.StatusTooManyRequests, resend(t, `{"email":"Throttled@example.com"}`))
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestBookmarks(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Bookmarks = &store.MockBookmarkStore{Collections: map[int64]*store.BookmarkCollection{
+		1: {ID: 1, UserID: 1, Name: "saved"},
+		2: {ID: 2, UserID: 2, Name: "saved"},
+	}}
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"creating a collection", http.MethodPost, "/v1/users/me/bookmarks", `{"name":"later"}`, http.StatusCreated},
+		{"creating a collection twice", http.MethodPost, "/v1/users/me/bookmarks", `{"name":"saved"}`, http.StatusConflict},
+		{"creating a collection without a name", http.MethodPost, "/v1/users/me/bookmarks", `{"name":""}`, http.StatusBadRequest},
+		{"listing an own collection", http.MethodGet, "/v1/users/me/bookmarks/1/posts", "", http.StatusOK},
+		{"saving to an own collection", http.MethodPut, "/v1/users/me/bookmarks/1/posts/1", "", http.StatusNoContent},
+		{"removing from an own collection", http.MethodDelete, "/v1/users/me/bookmarks/1/posts/1", "", http.StatusNoContent},
+		{"listing a collection of another user", http.MethodGet, "/v1/users/me/bookmarks/2/posts", "", http.StatusNotFound},
+		{"saving to a collection of another user", http.MethodPut, "/v1/users/me/bookmarks/2/posts/1", "", http.StatusNotFound},
+		{"removing from a collection of another user", http.MethodDelete, "/v1/users/me/bookmarks/2/posts/1", "", http.StatusNotFound},
+		{"deleting a collection of another user", http.MethodDelete, "/v1/users/me/bookmarks/2", "", http.StatusNotFound},
+		{"deleting an own collection", http.MethodDelete, "/v1/users/me/bookmarks/1", "", http.StatusNoContent},
+		{"invalid post", http.MethodPut, "/v1/users/me/bookmarks/1/posts/first", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+
+			req, err := http.NewRequest(tt.method, tt.path, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
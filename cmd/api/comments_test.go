@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestCommentOwnership(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &store.MockPostStore{Posts: map[int64]*store.Post{
+		1: {ID: 1, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPublic},
+	}}
+	app.store.Comments = &store.MockCommentStore{Comments: map[int64]*store.Comment{
+		1: {ID: 1, PostID: 1, UserID: 1},
+		2: {ID: 2, PostID: 1, UserID: 2},
+	}}
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		permissions []string
+		method      string
+		commentID   string
+		code        int
+	}{
+		{"updating an own comment", nil, http.MethodPatch, "1", http.StatusOK},
+		{"deleting an own comment", nil, http.MethodDelete, "1", http.StatusNoContent},
+		{"updating a comment of another user", nil, http.MethodPatch, "2", http.StatusForbidden},
+		{"deleting a comment of another user", nil, http.MethodDelete, "2", http.StatusForbidden},
+		{"moderator updating a comment of another user", []string{store.PermissionCommentUpdateAny}, http.MethodPatch, "2", http.StatusOK},
+		{"moderator deleting a comment of another user", []string{store.PermissionCommentDeleteAny}, http.MethodDelete, "2", http.StatusNoContent},
+		{"updating with the permission to delete", []string{store.PermissionCommentDeleteAny}, http.MethodPatch, "2", http.StatusForbidden},
+		{"missing comment", nil, http.MethodDelete, "3", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.store.Users = &store.MockUserStore{Users: map[int64]*store.User{
+				1: {ID: 1, Role: store.Role{Permissions: tt.permissions}},
+			}}
+
+			req, err := http.NewRequest(tt.method, "/v1/comments/"+tt.commentID, strings.NewReader(`{"content":"edited"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
+
+func TestCreateComment(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &store.MockPostStore{Posts: map[int64]*store.Post{
+		1: {ID: 1, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPublic},
+	}}
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"comment", `{"content":"hi"}`, http.StatusCreated},
+		{"reply", `{"content":"hi","parent_id":1}`, http.StatusCreated},
+		{"empty", `{"content":""}`, http.StatusBadRequest},
+		{"invalid parent", `{"content":"hi","parent_id":0}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/posts/1/comments", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
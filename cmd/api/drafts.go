@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+type DraftPage struct {
+	Posts      []store.Post `json:"posts"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	PrevCursor string       `json:"prev_cursor,omitempty"`
+}
+
+// listDraftsHandler pages through the user's drafts and scheduled posts, most
+// recent first by default.
+func (app *application) listDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	cq := store.CursorQuery{
+		Limit: 20,
+		Sort:  "desc",
+	}
+
+	cq, err := cq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	posts, err := app.store.Posts.ListUnpublished(r.Context(), user.ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	posts, next, prev := store.Paginate(posts, cq, func(post store.Post) (string, int64) {
+		return post.CreatedAt, post.ID
+	})
+
+	page := DraftPage{Posts: posts}
+	page.NextCursor, page.PrevCursor = app.pageCursors(w, r, next, prev)
+
+	list := make([]*store.Post, len(page.Posts))
+	for i := range page.Posts {
+		list[i] = &page.Posts[i]
+	}
+
+	if err := app.store.Posts.AttachDetails(r.Context(), list, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// publishScheduledPosts periodically publishes the scheduled posts that are
+// due, until ctx is done. Every API instance runs it, the store makes sure
+// they do not step on each other.
+func (app *application) publishScheduledPosts(ctx context.Context) {
+	ticker := time.NewTicker(app.config.posts.publishInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// drain the backlog in batches
+			for {
+				ids, err := app.store.Posts.PublishDue(ctx, app.config.posts.publishBatch)
+				if err != nil {
+					app.logger.Errorw("error publishing scheduled posts", "error", err)
+					break
+				}
+
+				if len(ids) > 0 {
+					app.logger.Infow("published scheduled posts", "posts", ids)
+				}
+
+				for _, id := range ids {
+					post, err := app.store.Posts.GetByID(ctx, id)
+					if err != nil {
+						app.logger.Errorw("error reading published post", "post", id, "error", err)
+						continue
+					}
+					app.fanOutPost(post)
+				}
+
+				if len(ids) < app.config.posts.publishBatch {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+// postETag identifies the version of a post. Reactions are left out, they
+// change without the post being edited.
+func postETag(post *store.Post) string {
+	return fmt.Sprintf(`"%d"`, post.Version)
+}
+
+// postReadETag identifies a post as read by a viewer, details included:
+// reactions, attachments and polls change without the post being edited, and
+// part of them depends on the viewer. It starts with the version of the post
+// so edits can still be checked against it.
+func postReadETag(post *store.Post, viewerID int64) (string, error) {
+	body, err := json.Marshal(post)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", viewerID)
+	h.Write(body)
+
+	return fmt.Sprintf(`W/"%d-%x"`, post.Version, h.Sum(nil)[:8]), nil
+}
+
+// etagMatches reports whether etag is listed in an If-None-Match header,
+// which compares weakly.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// versionMatches reports whether an If-Match header lists a tag of the given
+// version of a post: its version tag or the tag of a read of it, whatever the
+// details were then.
+func versionMatches(header string, version int) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == "*" {
+			return true
+		}
+
+		tag, ok := strings.CutPrefix(candidate, `"`)
+		if !ok {
+			continue
+		}
+		tag, ok = strings.CutSuffix(tag, `"`)
+		if !ok {
+			continue
+		}
+
+		v, _, _ := strings.Cut(tag, "-")
+		if v == strconv.Itoa(version) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkPostPrecondition rejects writes carrying an If-Match that no longer
+// matches the post, so editors working from a stale copy get a 412 instead of
+// overwriting each other. Only edits count, reacting to a post or voting in
+// its poll does not make a copy stale.
+func (app *application) checkPostPrecondition(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post := getPostFromCtx(r)
+
+		if header := r.Header.Get("If-Match"); header != "" && !versionMatches(header, post.Version) {
+			app.preconditionFailedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
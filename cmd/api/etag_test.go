@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		match  bool
+	}{
+		{`W/"3-abc"`, true},
+		{`"3-abc"`, true},
+		{`W/"3-abd"`, false},
+		{`"1", W/"3-abc"`, true},
+		{`*`, true},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `W/"3-abc"`); got != tt.match {
+			t.Errorf("etagMatches(%s) = %v, expected %v", tt.header, got, tt.match)
+		}
+	}
+}
+
+func TestVersionMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		match  bool
+	}{
+		{`"3"`, true},
+		{`"2"`, false},
+		{`"1", "3"`, true},
+		{`*`, true},
+		{`W/"3-0123abcd"`, true},
+		{`W/"2-0123abcd"`, false},
+		{`"33"`, false},
+		{`3`, false},
+	}
+
+	for _, tt := range tests {
+		if got := versionMatches(tt.header, 3); got != tt.match {
+			t.Errorf("versionMatches(%s) = %v, expected %v", tt.header, got, tt.match)
+		}
+	}
+}
+
+func TestPostReadETag(t *testing.T) {
+	post := &store.Post{ID: 1, Version: 3}
+
+	etag := func(post *store.Post, viewerID int64) string {
+		t.Helper()
+		tag, err := postReadETag(post, viewerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tag
+	}
+
+	base := etag(post, 1)
+	if !versionMatches(base, 3) {
+		t.Errorf("expected %s to match version 3", base)
+	}
+
+	if etag(post, 2) == base {
+		t.Error("expected the tag to depend on the viewer")
+	}
+
+	post.Attachments = []store.Attachment{{ID: 1}}
+	if etag(post, 1) == base {
+		t.Error("expected the tag to change with the attachments")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/umeh-promise/social/internal/store"
@@ -10,12 +11,14 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 	// pagination, filters, sort
 
 	fq := store.PaginatedFeedQuery{
-		Limit:  20,
-		Offset: 0,
-		Sort:   "desc",
+		CursorQuery: store.CursorQuery{
+			Limit: 20,
+			Sort:  "desc",
+		},
+		Mode: store.FeedModeLatest,
 	}
 
-	fq, err := fq.Parse(r)
+	fq, err := fq.Parse(r, app.cursors)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -26,14 +29,30 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	ctx := r.Context()
-	feeds, err := app.store.Posts.GetUserFeed(ctx, int64(312), fq)
+	if fq.Since != "" && fq.Until != "" && fq.Since >= fq.Until {
+		app.badRequestResponse(w, r, errors.New("since must be before until"))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if fq.Mode == store.FeedModeRanked {
+		app.rankedFeed(w, r, user, fq)
+		return
+	}
+
+	feed, err := app.getUserFeed(r.Context(), user.ID, fq)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, feeds); err != nil {
+	feed, next, prev := store.Paginate(feed, fq.CursorQuery, postPosition)
+
+	page := PostPage{Posts: feed}
+	page.NextCursor, page.PrevCursor = app.pageCursors(w, r, next, prev)
+
+	if err := app.jsonResponse(w, http.StatusOK, page); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
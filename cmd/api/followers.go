@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+type FollowPage struct {
+	Users      []store.FollowEntry `json:"users"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+	PrevCursor string              `json:"prev_cursor,omitempty"`
+}
+
+// listFollowersHandler pages through the followers of a user, most recent
+// first by default.
+func (app *application) listFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.store.Followers.ListFollowers)
+}
+
+// listFollowingHandler pages through the users a user follows, most recent
+// first by default.
+func (app *application) listFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.store.Followers.ListFollowing)
+}
+
+func (app *application) listFollows(w http.ResponseWriter, r *http.Request, list func(context.Context, int64, store.CursorQuery) ([]store.FollowEntry, error)) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || userID < 1 {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	cq := store.CursorQuery{
+		Limit: 20,
+		Sort:  "desc",
+	}
+
+	cq, err = cq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if _, err := app.getUser(r.Context(), userID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	users, err := list(r.Context(), userID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	users, next, prev := store.Paginate(users, cq, func(user store.FollowEntry) (string, int64) {
+		return user.FollowedAt, user.ID
+	})
+
+	page := FollowPage{Users: users}
+	page.NextCursor, page.PrevCursor = app.pageCursors(w, r, next, prev)
+
+	if err := app.jsonResponse(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
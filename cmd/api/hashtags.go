@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/umeh-promise/social/internal/markup"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+// listHashtagPostsHandler pages through the posts with a hashtag, newest
+// first by default.
+func (app *application) listHashtagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	tag, ok := markup.NormalizeHashtag(chi.URLParam(r, "tag"))
+	if !ok {
+		app.badRequestResponse(w, r, errors.New("invalid hashtag"))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	app.listPostPage(w, r, func(cq store.CursorQuery) ([]store.PostWithMetadata, error) {
+		return app.store.Posts.ListByHashtag(r.Context(), tag, user.ID, cq)
+	})
+}
+
+// listMentionsHandler pages through the posts mentioning the user, newest
+// first by default.
+func (app *application) listMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	app.listPostPage(w, r, func(cq store.CursorQuery) ([]store.PostWithMetadata, error) {
+		return app.store.Posts.ListMentioning(r.Context(), user.ID, cq)
+	})
+}
+
+// listPostPage writes the page of posts returned by list for the cursor of
+// the request.
+func (app *application) listPostPage(w http.ResponseWriter, r *http.Request, list func(store.CursorQuery) ([]store.PostWithMetadata, error)) {
+	cq := store.CursorQuery{
+		Limit: 20,
+		Sort:  "desc",
+	}
+
+	cq, err := cq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	posts, err := list(cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	posts, next, prev := store.Paginate(posts, cq, postPosition)
+
+	page := PostPage{Posts: posts}
+	page.NextCursor, page.PrevCursor = app.pageCursors(w, r, next, prev)
+
+	if err := app.jsonResponse(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
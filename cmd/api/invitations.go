@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/umeh-promise/social/internal/mailer"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+type ResendActivationPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+func (app *application) resendActivationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResendActivationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// throttle per address, whether or not it is registered, so the limit
+	// doesn't reveal which accounts exist
+	if allow, retryAfter := app.activationLimiter.Allow(strings.ToLower(payload.Email)); !allow {
+		app.rateLimitExceededResponse(w, r, retryAfter.String())
+		return
+	}
+
+	message := "if an account with that email is awaiting activation, a new activation link has been sent"
+
+	ctx := r.Context()
+	user, err := app.store.Users.GetByEmail(ctx, payload.Email)
+	if err != nil && err != store.ErrorNotFound {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if user == nil || user.IsActive {
+		if err := app.jsonResponse(w, http.StatusAccepted, message); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	plainToken := uuid.New().String()
+
+	// links from earlier emails stop working
+	if err := app.store.Users.ReplaceInvitation(ctx, user.ID, plainToken, app.config.mail.exp); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username      string
+		ActivationURL string
+	}{
+		Username:      user.Username,
+		ActivationURL: fmt.Sprintf("%s/confirm/%s", app.config.frontendURL, plainToken),
+	}
+
+	go func() {
+		if err := app.mailer.Send(mailer.UserWelcomeTemplate, user.Username, user.Email, vars, !isProdEnv); err != nil {
+			app.logger.Errorw("error resending activation email", "error", err)
+		}
+	}()
+
+	if err := app.jsonResponse(w, http.StatusAccepted, message); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// sweepInactiveUsers periodically purges expired invitations and accounts that
+// were never activated, until ctx is done.
+func (app *application) sweepInactiveUsers(ctx context.Context) {
+	ticker := time.NewTicker(app.config.mail.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			invitations, users, err := app.store.Users.PurgeInactive(ctx, app.config.mail.inactiveGrace)
+			if err != nil {
+				app.logger.Errorw("error purging inactive users", "error", err)
+				continue
+			}
+
+			if invitations > 0 || users > 0 {
+				app.logger.Infow("purged inactive users", "invitations", invitations, "users", users)
+			}
+		}
+	}
+}
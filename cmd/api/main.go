@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"expvar"
+	"fmt"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/umeh-promise/social/internal/auth"
+	"github.com/umeh-promise/social/internal/blob"
 	"github.com/umeh-promise/social/internal/db"
 	"github.com/umeh-promise/social/internal/env"
 	"github.com/umeh-promise/social/internal/mailer"
+	"github.com/umeh-promise/social/internal/ranking"
 	"github.com/umeh-promise/social/internal/ratelimiter"
 	"github.com/umeh-promise/social/internal/store"
 	"github.com/umeh-promise/social/internal/store/cache"
@@ -56,10 +62,13 @@ func main() {
 		env: env.GetString("ENV", "development"),
 		mail: mailConfig{
 			exp:       time.Hour * 24 * 3, // 3 days
+			resetExp:  time.Hour,
 			fromEmail: env.GetString("FROM_EMAIL", ""),
 			sendGrid: sendGridConfig{
 				apikey: env.GetString("SENDGRID_API_KEY", ""),
 			},
+			inactiveGrace: env.GetDuration("INACTIVE_USER_GRACE_PERIOD", time.Hour*24*7),
+			sweepInterval: env.GetDuration("INACTIVE_USER_SWEEP_INTERVAL", time.Hour),
 		},
 
 		auth: authConfig{
@@ -68,9 +77,17 @@ func main() {
 				password: env.GetString("AUTH_BASIC_PASSWORD", "admin"),
 			},
 			token: tokenConfig{
-				secret: env.GetString("AUTH_SECRET", "basic"),
-				exp:    time.Hour * 24 * 3,
-				issuer: "social-network",
+				keysDir:         env.GetString("AUTH_JWT_KEYS_DIR", ""),
+				keyPublishDelay: env.GetDuration("AUTH_JWT_KEY_PUBLISH_DELAY", time.Minute*10),
+				keyReload:       time.Minute,
+				exp:             time.Minute * 15,
+				challengeExp:    time.Minute * 5,
+				refreshExp:      time.Hour * 24 * 30, // 30 days
+				issuer:          "social-network",
+			},
+			oauth: oauthConfig{
+				stateExp:  time.Minute * 10,
+				providers: oidcProvidersFromEnv(),
 			},
 		},
 		rateLimiter: ratelimiter.Config{
@@ -78,6 +95,35 @@ func main() {
 			TimeFrame:           time.Second * 5,
 			Enabled:             env.GetBool("RATELIMITER_ENABLED", true),
 		},
+		media: mediaConfig{
+			backend: env.GetString("MEDIA_BACKEND", "local"),
+			dir:     env.GetString("MEDIA_DIR", "./media"),
+			s3: blob.S3Config{
+				Endpoint:  env.GetString("MEDIA_S3_ENDPOINT", ""),
+				Region:    env.GetString("MEDIA_S3_REGION", "us-east-1"),
+				Bucket:    env.GetString("MEDIA_S3_BUCKET", ""),
+				AccessKey: env.GetString("MEDIA_S3_ACCESS_KEY", ""),
+				SecretKey: env.GetString("MEDIA_S3_SECRET_KEY", ""),
+				PathStyle: env.GetBool("MEDIA_S3_PATH_STYLE", true),
+			},
+			maxUploadSize:  10 << 20, // 10MB
+			maxAttachments: 4,
+			thumbnailSize:  320,
+		},
+		feed: feedConfig{
+			maxFanOutFollowers: env.GetInt("FEED_FANOUT_MAX_FOLLOWERS", 10000),
+			rankingHalfLife:    env.GetDuration("FEED_RANKING_HALF_LIFE", time.Hour*6),
+			rankingWindow:      time.Hour * 72,
+			rankingCandidates:  500,
+			affinityWindow:     time.Hour * 24 * 30,
+		},
+		pagination: paginationConfig{
+			cursorSecret: env.GetString("CURSOR_SECRET", ""),
+		},
+		posts: postsConfig{
+			publishInterval: env.GetDuration("POST_PUBLISH_INTERVAL", time.Second*30),
+			publishBatch:    100,
+		},
 	}
 
 	// Rate limiter
@@ -85,6 +131,12 @@ func main() {
 		config.rateLimiter.RequestPerTimeFrame, config.rateLimiter.TimeFrame,
 	)
 
+	// Second factor attempts per account
+	twoFactorLimiter := ratelimiter.NewFixedWindowLimiter(5, time.Minute)
+
+	// Activation emails per address
+	activationLimiter := ratelimiter.NewFixedWindowLimiter(3, time.Hour)
+
 	// Logger
 	logger := zap.Must(zap.NewProduction()).Sugar()
 	defer logger.Sync()
@@ -111,12 +163,81 @@ func main() {
 		logger.Info("redis cache connection pool is established")
 	}
 
+	// Pagination cursors
+	cursorSecret := []byte(config.pagination.cursorSecret)
+	if len(cursorSecret) == 0 {
+		if config.env == "production" {
+			logger.Fatal("CURSOR_SECRET must be set in production")
+		}
+		cursorSecret = make([]byte, 32)
+		if _, err := rand.Read(cursorSecret); err != nil {
+			logger.Fatal(err)
+		}
+		logger.Warn("CURSOR_SECRET is not set, signing cursors with an ephemeral key")
+	}
+	cursors := store.NewCursorCodec(cursorSecret)
+
 	store := store.NewStore(db)
 	cacheStorage := cache.NewCacheStorage(rdb)
 
 	mailer := mailer.NewSendgrid(config.mail.sendGrid.apikey, config.mail.fromEmail)
 
-	jwtAuthenticator := auth.NewJWTAuthenticator(config.auth.token.secret, config.auth.token.issuer, config.auth.token.issuer)
+	// Signing keys
+	var keyRing *auth.KeyRing
+	if config.auth.token.keysDir != "" {
+		keyRing, err = auth.LoadKeyRing(config.auth.token.keysDir, config.auth.token.keyPublishDelay)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		go keyRing.Watch(context.Background(), config.auth.token.keyReload, func(err error) {
+			logger.Errorw("error reloading signing keys", "error", err)
+		})
+	} else {
+		if config.env == "production" {
+			logger.Fatal("AUTH_JWT_KEYS_DIR must be set in production")
+		}
+		keyRing, err = auth.NewEphemeralKeyRing()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Warn("AUTH_JWT_KEYS_DIR is not set, signing tokens with an ephemeral key")
+	}
+
+	jwtAuthenticator := auth.NewJWTAuthenticator(keyRing, config.auth.token.issuer, config.auth.token.issuer)
+
+	// External identity providers
+	identityProviders := make(map[string]auth.IdentityProvider, len(config.auth.oauth.providers))
+	for _, p := range config.auth.oauth.providers {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		provider, err := auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+			Name:         p.name,
+			IssuerURL:    p.issuer,
+			ClientID:     p.clientID,
+			ClientSecret: p.clientSecret,
+			RedirectURL:  p.redirectURL,
+		})
+		cancel()
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		identityProviders[p.name] = provider
+		logger.Infow("identity provider configured", "provider", p.name)
+	}
+
+	// Media storage
+	var blobStore blob.Store
+	switch config.media.backend {
+	case "s3":
+		blobStore, err = blob.NewS3Store(config.media.s3)
+	case "local":
+		blobStore, err = blob.NewLocalStore(config.media.dir)
+	default:
+		err = fmt.Errorf("unknown media backend %q", config.media.backend)
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
 
 	app := &application{
 		config:        config,
@@ -126,8 +247,18 @@ func main() {
 		mailer:        mailer,
 		authenticator: jwtAuthenticator,
 		rateLimiter:   rateLimiter,
+
+		twoFactorLimiter:  twoFactorLimiter,
+		identityProviders: identityProviders,
+		activationLimiter: activationLimiter,
+		blobStore:         blobStore,
+		cursors:           cursors,
+		ranker:            ranking.NewRanker(config.feed.rankingHalfLife),
 	}
 
+	go app.sweepInactiveUsers(context.Background())
+	go app.publishScheduledPosts(context.Background())
+
 	expvar.NewString("version").Set(version)
 	expvar.Publish("database", expvar.Func(func() any {
 		return db.Stats()
@@ -140,3 +271,26 @@ func main() {
 
 	logger.Fatal(app.run(router))
 }
+
+// oidcProvidersFromEnv reads the providers listed in OIDC_PROVIDERS, each one
+// configured through OIDC_<NAME>_* variables.
+func oidcProvidersFromEnv() []oidcProviderConfig {
+	var providers []oidcProviderConfig
+	for _, name := range strings.Split(env.GetString("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, oidcProviderConfig{
+			name:         name,
+			issuer:       env.GetString(prefix+"ISSUER", ""),
+			clientID:     env.GetString(prefix+"CLIENT_ID", ""),
+			clientSecret: env.GetString(prefix+"CLIENT_SECRET", ""),
+			redirectURL:  env.GetString(prefix+"REDIRECT_URL", fmt.Sprintf("http://%s/v1/auth/oauth/%s/callback", env.GetString("EXTERNAL_URL", "localhost:8080"), name)),
+		})
+	}
+
+	return providers
+}
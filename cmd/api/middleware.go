@@ -36,13 +36,45 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 		}
 
 		claims := jwtToken.Claims.(jwt.MapClaims)
+		if typ, ok := claims["typ"]; ok && typ != accessTokenType {
+			app.unathorizedErrorResponse(w, r, fmt.Errorf("token cannot be used for authentication"))
+			return
+		}
+
 		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
 		if err != nil {
 			app.unathorizedErrorResponse(w, r, err)
 			return
 		}
 
+		// reject tokens whose session was revoked (logout, refresh token reuse)
+		sessionID, _ := claims["sid"].(string)
+		if sessionID == "" {
+			app.unathorizedErrorResponse(w, r, fmt.Errorf("token is not bound to a session"))
+			return
+		}
+
 		ctx := r.Context()
+		session, err := app.store.Sessions.GetByID(ctx, sessionID)
+		if err != nil {
+			switch err {
+			case store.ErrorNotFound:
+				app.unathorizedErrorResponse(w, r, fmt.Errorf("session not found"))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if session.RevokedAt != nil || session.UserID != userID {
+			app.unathorizedErrorResponse(w, r, fmt.Errorf("session has been revoked"))
+			return
+		}
+
+		if err := app.store.Sessions.Touch(ctx, session.ID, clientIP(r)); err != nil {
+			app.logger.Errorw("error updating session last seen", "error", err)
+		}
+
 		user, err := app.getUser(ctx, userID)
 		if err != nil {
 			app.unathorizedErrorResponse(w, r, err)
@@ -50,6 +82,7 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 		}
 
 		ctx = context.WithValue(ctx, userCtx, user)
+		ctx = context.WithValue(ctx, sessionCtx, session)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -94,7 +127,9 @@ func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 
 }
 
-func (app *application) checkPostOwnership(requiredRole string, next http.HandlerFunc) http.HandlerFunc {
+// checkPostOwnership lets the author through, and anyone else only with the
+// permission to act on posts of other users.
+func (app *application) checkPostOwnership(permission string, next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := getUserFromContext(r)
 		post := getPostFromCtx(r)
@@ -105,14 +140,7 @@ func (app *application) checkPostOwnership(requiredRole string, next http.Handle
 			return
 		}
 
-		// role precedence check
-		allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
-		if err != nil {
-			app.internalServerError(w, r, err)
-			return
-		}
-
-		if !allowed {
+		if !user.Role.HasPermission(permission) {
 			app.forbiddenResponseError(w, r)
 			return
 		}
@@ -121,13 +149,20 @@ func (app *application) checkPostOwnership(requiredRole string, next http.Handle
 	})
 }
 
-func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
-	role, err := app.store.Roles.GetByName(ctx, roleName)
-	if err != nil {
-		return false, err
-	}
+// RequirePermission restricts a route to users whose role has the permission.
+func (app *application) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getUserFromContext(r)
+
+			if !user.Role.HasPermission(permission) {
+				app.forbiddenResponseError(w, r)
+				return
+			}
 
-	return user.Role.Level >= role.Level, nil
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 func (app *application) getUser(ctx context.Context, userID int64) (*store.User, error) {
@@ -141,7 +176,7 @@ func (app *application) getUser(ctx context.Context, userID int64) (*store.User,
 	}
 
 	if user == nil {
-		user, err := app.store.Users.GetByID(ctx, userID)
+		user, err = app.store.Users.GetByID(ctx, userID)
 		if err != nil {
 			return nil, err
 		}
@@ -154,6 +189,18 @@ func (app *application) getUser(ctx context.Context, userID int64) (*store.User,
 
 }
 
+// invalidateUser drops the cached copy of a user after a change that affects
+// authorization.
+func (app *application) invalidateUser(ctx context.Context, userID int64) {
+	if !app.config.cache.enabled {
+		return
+	}
+
+	if err := app.cacheStorage.Users.Delete(ctx, userID); err != nil {
+		app.logger.Errorw("error invalidating cached user", "user", userID, "error", err)
+	}
+}
+
 func (app *application) RateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.config.rateLimiter.Enabled {
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/umeh-promise/social/internal/auth"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+const (
+	maxUsernameLength        = 30
+	maxUsernameAttempts      = 5
+	identityProviderNotFound = "identity provider not found"
+)
+
+var (
+	errEmailNotVerified = errors.New("the identity provider has not verified this email address")
+	usernameInvalidChar = regexp.MustCompile(`[^a-z0-9_.]+`)
+)
+
+// startOAuthHandler redirects the browser to the identity provider. The state,
+// nonce and PKCE verifier are kept server side until the callback.
+func (app *application) startOAuthHandler(w http.ResponseWriter, r *http.Request) {
+	provider, ok := app.identityProviders[chi.URLParam(r, "provider")]
+	if !ok {
+		app.notFoundResponse(w, r, errors.New(identityProviderNotFound))
+		return
+	}
+
+	state, err := generateOpaqueToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	verifier, challenge, err := auth.NewPKCE()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	oauthState := &store.OAuthState{
+		State:        state,
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}
+	if err := app.store.Identities.CreateState(r.Context(), oauthState, app.config.auth.oauth.stateExp); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, provider.AuthCodeURL(state, nonce, challenge), http.StatusFound)
+}
+
+func (app *application) oauthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	provider, ok := app.identityProviders[chi.URLParam(r, "provider")]
+	if !ok {
+		app.notFoundResponse(w, r, errors.New(identityProviderNotFound))
+		return
+	}
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		app.unathorizedErrorResponse(w, r, fmt.Errorf("login with %s failed: %s", provider.Name(), providerErr))
+		return
+	}
+
+	code, state := query.Get("code"), query.Get("state")
+	if code == "" || state == "" {
+		app.badRequestResponse(w, r, errors.New("missing code or state"))
+		return
+	}
+
+	ctx := r.Context()
+
+	oauthState, err := app.store.Identities.ConsumeState(ctx, state, provider.Name())
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.unathorizedErrorResponse(w, r, errors.New("invalid or expired login state"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	identity, err := provider.Exchange(ctx, code, oauthState.CodeVerifier, oauthState.Nonce)
+	if err != nil {
+		app.unathorizedErrorResponse(w, r, err)
+		return
+	}
+
+	user, err := app.userForIdentity(r, identity)
+	if err != nil {
+		switch err {
+		case errEmailNotVerified:
+			app.unathorizedErrorResponse(w, r, err)
+		case store.ErrorConflict, store.ErrorDuplicateEmail:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !user.IsActive {
+		app.unathorizedErrorResponse(w, r, store.ErrorAccountNotActivated)
+		return
+	}
+
+	app.completeLogin(w, r, user)
+}
+
+// userForIdentity finds the user an external identity belongs to. Unknown
+// identities are linked to the user with the same email, or get a new account,
+// but only when the provider vouches for the address.
+func (app *application) userForIdentity(r *http.Request, identity *auth.Identity) (*store.User, error) {
+	ctx := r.Context()
+
+	userID, err := app.store.Identities.GetUserID(ctx, identity.Provider, identity.Subject)
+	switch err {
+	case nil:
+		return app.store.Users.GetByID(ctx, userID)
+	case store.ErrorNotFound:
+	default:
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errEmailNotVerified
+	}
+
+	link := &store.Identity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	user, err := app.store.Users.GetByEmail(ctx, identity.Email)
+	switch err {
+	case nil:
+		if err := app.store.Identities.Link(ctx, user, link); err != nil {
+			return nil, err
+		}
+
+		app.invalidateUser(ctx, user.ID)
+		app.logger.Infow("linked external identity", "provider", identity.Provider, "user", user.ID)
+		return user, nil
+	case store.ErrorNotFound:
+	default:
+		return nil, err
+	}
+
+	return app.createUserFromIdentity(r, identity, link)
+}
+
+func (app *application) createUserFromIdentity(r *http.Request, identity *auth.Identity, link *store.Identity) (*store.User, error) {
+	// the account has no usable password until the user resets it
+	password, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	base := usernameFromIdentity(identity)
+	for attempt := 0; attempt < maxUsernameAttempts; attempt++ {
+		username := base
+		if attempt > 0 {
+			n, err := rand.Int(rand.Reader, big.NewInt(10000))
+			if err != nil {
+				return nil, err
+			}
+			username = fmt.Sprintf("%s_%04d", base, n.Int64())
+		}
+
+		user := &store.User{
+			Email:    identity.Email,
+			Username: username,
+			Role: store.Role{
+				Name: "user",
+			},
+		}
+		if err := user.Password.Set(password); err != nil {
+			return nil, err
+		}
+
+		err := app.store.Identities.CreateUser(r.Context(), user, link)
+		switch err {
+		case nil:
+			app.logger.Infow("created user from external identity", "provider", identity.Provider, "user", user.ID)
+			return user, nil
+		case store.ErrorDuplicateUsername:
+			continue
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, store.ErrorDuplicateUsername
+}
+
+func usernameFromIdentity(identity *auth.Identity) string {
+	name := identity.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	name = usernameInvalidChar.ReplaceAllString(strings.ToLower(name), "")
+	if len(name) > maxUsernameLength-5 {
+		// leave room for the suffix added on collisions
+		name = name[:maxUsernameLength-5]
+	}
+	if len(name) < 2 {
+		name = "user"
+	}
+
+	return name
+}
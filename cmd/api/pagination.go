@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+type PostPage struct {
+	Posts      []store.PostWithMetadata `json:"posts"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+	PrevCursor string                   `json:"prev_cursor,omitempty"`
+}
+
+// postPosition is the position of a post in a list, for store.Paginate.
+func postPosition(post store.PostWithMetadata) (string, int64) {
+	return post.CreatedAt, post.ID
+}
+
+// pageCursors encodes the cursors to the pages after and before the current
+// one, and links to them with RFC 8288 Link headers.
+func (app *application) pageCursors(w http.ResponseWriter, r *http.Request, next, prev *store.Cursor) (nextCursor, prevCursor string) {
+	link := func(cursor *store.Cursor, rel string) string {
+		encoded := app.cursors.Encode(*cursor)
+
+		query := r.URL.Query()
+		query.Set("cursor", encoded)
+		// the cursor carries the order
+		query.Del("sort")
+
+		target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel))
+
+		return encoded
+	}
+
+	if next != nil {
+		nextCursor = link(next, "next")
+	}
+	if prev != nil {
+		prevCursor = link(prev, "prev")
+	}
+
+	return nextCursor, prevCursor
+}
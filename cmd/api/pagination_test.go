@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestPageCursors(t *testing.T) {
+	app := newTestApplication(t)
+
+	r := httptest.NewRequest("GET", "/v1/users/feed?limit=5&sort=asc&tags=go", nil)
+	w := httptest.NewRecorder()
+
+	next := &store.Cursor{CreatedAt: "2024-01-01T00:00:00Z", ID: 2, Sort: "asc"}
+	prev := &store.Cursor{CreatedAt: "2024-01-01T00:00:00Z", ID: 1, Sort: "asc", Prev: true}
+	nextCursor, prevCursor := app.pageCursors(w, r, next, prev)
+
+	links := w.Result().Header.Values("Link")
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %v", links)
+	}
+
+	for i, tt := range []struct {
+		rel    string
+		cursor string
+		want   *store.Cursor
+	}{
+		{"next", nextCursor, next},
+		{"prev", prevCursor, prev},
+	} {
+		target, ok := strings.CutSuffix(links[i], `>; rel="`+tt.rel+`"`)
+		if !ok || !strings.HasPrefix(target, "<") {
+			t.Fatalf("unexpected link %q", links[i])
+		}
+
+		u, err := url.Parse(target[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query := u.Query()
+		if u.Path != "/v1/users/feed" || query.Get("limit") != "5" || query.Get("tags") != "go" || query.Has("sort") {
+			t.Errorf("unexpected %s link %q", tt.rel, links[i])
+		}
+		if query.Get("cursor") != tt.cursor {
+			t.Errorf("%s link cursor %q, expected %q", tt.rel, query.Get("cursor"), tt.cursor)
+		}
+
+		cursor, err := app.cursors.Decode(tt.cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cursor != *tt.want {
+			t.Errorf("%s cursor %+v, expected %+v", tt.rel, cursor, *tt.want)
+		}
+	}
+}
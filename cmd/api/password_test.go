@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestForgotPassword(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	forgot := func(t *testing.T, body string) int {
+		req, err := http.NewRequest(http.MethodPost, "/v1/auth/password/forgot", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should not reveal unknown emails", func(t *testing.T) {
+		checkResponseCode(t, http.StatusAccepted, forgot(t, `{"email":"nobody@example.com"}`))
+	})
+
+	t.Run("should reject malformed payloads", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, forgot(t, `{"email":"not-an-email"}`))
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	reset := func(t *testing.T, body string) int {
+		req, err := http.NewRequest(http.MethodPost, "/v1/auth/password/reset", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should reset the password", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNoContent, reset(t, `{"token":"token","password":"secret123"}`))
+	})
+
+	t.Run("should reject short passwords", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, reset(t, `{"token":"token","password":"short"}`))
+	})
+
+	t.Run("should require a token", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, reset(t, `{"password":"secret123"}`))
+	})
+}
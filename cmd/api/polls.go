@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+// maxPollDuration is how long a poll can stay open once its post is
+// published.
+const maxPollDuration = time.Hour * 24 * 30
+
+type CreatePollPayload struct {
+	Options  []string  `json:"options" validate:"min=2,max=4,dive,required,max=100"`
+	Multiple bool      `json:"multiple"`
+	ClosesAt time.Time `json:"closes_at" validate:"required"`
+}
+
+// newPoll makes the poll of a post, which opens when the post is published.
+func newPoll(payload *CreatePollPayload, post *store.Post) (*store.Poll, error) {
+	opensAt, err := publishTime(post)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPollCloses(payload.ClosesAt, opensAt); err != nil {
+		return nil, err
+	}
+
+	poll := &store.Poll{
+		Multiple: payload.Multiple,
+		ClosesAt: payload.ClosesAt.UTC().Format(time.RFC3339),
+		Options:  make([]store.PollOption, len(payload.Options)),
+	}
+
+	for i, text := range payload.Options {
+		poll.Options[i].Text = text
+	}
+
+	return poll, nil
+}
+
+// checkPostPoll checks the closing time of the poll of a post against the
+// time the post is published.
+func checkPostPoll(poll *store.Poll, post *store.Post) error {
+	closesAt, err := time.Parse(time.RFC3339, poll.ClosesAt)
+	if err != nil {
+		return err
+	}
+
+	opensAt, err := publishTime(post)
+	if err != nil {
+		return err
+	}
+
+	return checkPollCloses(closesAt, opensAt)
+}
+
+// checkPollCloses checks the closing time of a poll opening at opensAt.
+func checkPollCloses(closesAt, opensAt time.Time) error {
+	if !closesAt.After(time.Now()) {
+		return errors.New("closes_at must be in the future")
+	}
+
+	if !closesAt.After(opensAt) {
+		return errors.New("closes_at must be after publish_at")
+	}
+
+	if closesAt.Sub(opensAt) > maxPollDuration {
+		return fmt.Errorf("polls can stay open for at most %s", maxPollDuration)
+	}
+
+	return nil
+}
+
+// publishTime is when a post goes public: its publish time when it is
+// scheduled, now otherwise.
+func publishTime(post *store.Post) (time.Time, error) {
+	if post.Status != store.PostStatusScheduled || post.PublishAt == nil {
+		return time.Now(), nil
+	}
+
+	return time.Parse(time.RFC3339, *post.PublishAt)
+}
+
+type VotePayload struct {
+	OptionIDs []int64 `json:"option_ids" validate:"required,min=1,max=4"`
+}
+
+// votePollHandler casts the user's ballot and returns the poll with its
+// tallies, now visible to them.
+func (app *application) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	var payload VotePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+	ctx := r.Context()
+
+	if post.Status != store.PostStatusPublished {
+		app.badRequestResponse(w, r, errors.New("the post is not published"))
+		return
+	}
+
+	if err := app.store.Polls.Vote(ctx, post.ID, user.ID, payload.OptionIDs); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrorConflict:
+			app.conflictResponse(w, r, errors.New("already voted"))
+		case store.ErrorPollClosed, store.ErrorInvalidVote:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	poll, err := app.store.Polls.Get(ctx, post.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, poll); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
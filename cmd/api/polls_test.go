@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestNewPoll(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *string {
+		s := now.Add(d).UTC().Format(time.RFC3339)
+		return &s
+	}
+
+	tests := []struct {
+		name     string
+		post     *store.Post
+		closesAt time.Time
+		valid    bool
+	}{
+		{"published now", &store.Post{Status: store.PostStatusPublished}, now.Add(time.Hour), true},
+		{"closed already", &store.Post{Status: store.PostStatusPublished}, now.Add(-time.Hour), false},
+		{"open too long", &store.Post{Status: store.PostStatusPublished}, now.Add(maxPollDuration + time.Hour), false},
+		{"closing after publication", &store.Post{Status: store.PostStatusScheduled, PublishAt: at(24 * time.Hour)}, now.Add(48 * time.Hour), true},
+		{"closing before publication", &store.Post{Status: store.PostStatusScheduled, PublishAt: at(24 * time.Hour)}, now.Add(time.Hour), false},
+		{"open long from publication", &store.Post{Status: store.PostStatusScheduled, PublishAt: at(24 * time.Hour)}, now.Add(maxPollDuration + 12*time.Hour), true},
+		{"open too long from publication", &store.Post{Status: store.PostStatusScheduled, PublishAt: at(24 * time.Hour)}, now.Add(maxPollDuration + 48*time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := &CreatePollPayload{Options: []string{"yes", "no"}, ClosesAt: tt.closesAt}
+
+			_, err := newPoll(payload, tt.post)
+			if tt.valid && err != nil {
+				t.Errorf("got %v, expected a poll", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/umeh-promise/social/internal/store"
@@ -15,9 +16,13 @@ type postKey string
 const postCtx postKey = "post"
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" validate:"required,max=100"`
-	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags"`
+	Title      string             `json:"title" validate:"required,max=100"`
+	Content    string             `json:"content" validate:"required,max=1000"`
+	Tags       []string           `json:"tags"`
+	Status     string             `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt  *time.Time         `json:"publish_at"`
+	Visibility string             `json:"visibility" validate:"omitempty,oneof=public followers private unlisted"`
+	Poll       *CreatePollPayload `json:"poll"`
 }
 
 func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request) {
@@ -35,10 +40,34 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 	user := getUserFromContext(r)
 
 	post := &store.Post{
-		Title:   payload.Title,
-		Content: payload.Content,
-		Tags:    payload.Tags,
-		UserID:  user.ID,
+		Title:       payload.Title,
+		Content:     payload.Content,
+		Tags:        payload.Tags,
+		UserID:      user.ID,
+		Status:      store.PostStatusPublished,
+		Visibility:  store.PostVisibilityPublic,
+		Attachments: []store.Attachment{},
+	}
+
+	if payload.Status != "" {
+		post.Status = payload.Status
+	}
+	if payload.Visibility != "" {
+		post.Visibility = payload.Visibility
+	}
+
+	if err := setPublishAt(post, payload.PublishAt); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Poll != nil {
+		poll, err := newPoll(payload.Poll, post)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		post.Poll = poll
 	}
 
 	ctx := r.Context()
@@ -48,6 +77,10 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.fanOutPost(post)
+
+	w.Header().Set("ETag", postETag(post))
+
 	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -57,13 +90,27 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
-	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
+	user := getUserFromContext(r)
+
+	if err := app.store.Posts.AttachDetails(r.Context(), []*store.Post{post}, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	etag, err := postReadETag(post, user.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	post.Comments = comments
+	// the tag depends on who reads the post
+	w.Header().Set("Vary", "Authorization")
+	w.Header().Set("ETag", etag)
+
+	if header := r.Header.Get("If-None-Match"); header != "" && etagMatches(header, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
@@ -72,15 +119,24 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 type UpdatePostPayload struct {
-	Title   *string   `json:"title" validate:"omitempty,max=100"`
-	Content *string   `json:"content" validate:"omitempty,max=1000"`
-	Tags    *[]string `json:"tags"`
+	Title      *string    `json:"title" validate:"omitempty,max=100"`
+	Content    *string    `json:"content" validate:"omitempty,max=1000"`
+	Tags       *[]string  `json:"tags"`
+	Status     *string    `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt  *time.Time `json:"publish_at"`
+	Visibility *string    `json:"visibility" validate:"omitempty,oneof=public followers private unlisted"`
 }
 
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 	var payload UpdatePostPayload
 
 	post := getPostFromCtx(r)
+	published := post.Status == store.PostStatusPublished
+
+	if post.Kind == store.PostKindRepost {
+		app.badRequestResponse(w, r, errors.New("reposts cannot be edited"))
+		return
+	}
 
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
@@ -101,21 +157,72 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 	if payload.Tags != nil {
 		post.Tags = *payload.Tags
 	}
+	if payload.Visibility != nil {
+		post.Visibility = *payload.Visibility
+	}
+
+	if payload.Status != nil || payload.PublishAt != nil {
+		if post.Status == store.PostStatusPublished {
+			app.badRequestResponse(w, r, errors.New("the post is already published"))
+			return
+		}
+
+		if payload.Status != nil {
+			post.Status = *payload.Status
+		}
+
+		publishAt := payload.PublishAt
+		if publishAt == nil && post.Status == store.PostStatusScheduled && post.PublishAt != nil {
+			// keep the time the post was scheduled for
+			t, err := time.Parse(time.RFC3339, *post.PublishAt)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+			publishAt = &t
+		}
+
+		if err := setPublishAt(post, publishAt); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		// the poll of the post opens when it is published
+		poll, err := app.store.Polls.Get(r.Context(), post.ID, post.UserID)
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+		case err != nil:
+			app.internalServerError(w, r, err)
+			return
+		default:
+			if err := checkPostPoll(poll, post); err != nil {
+				app.badRequestResponse(w, r, err)
+				return
+			}
+		}
+	}
 
 	ctx := r.Context()
+	user := getUserFromContext(r)
 
-	err := app.store.Posts.Update(ctx, post)
+	err := app.store.Posts.Update(ctx, post, user.ID)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		switch {
+		case errors.Is(err, store.ErrorEditConflict):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
-	comments, err := app.store.Comments.GetByPostID(ctx, post.ID)
-	if err != nil {
-		app.internalServerError(w, r, err)
-		return
+	if !published {
+		app.fanOutPost(post)
 	}
-	post.Comments = comments
+
+	w.Header().Set("ETag", postETag(post))
 
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
@@ -135,6 +242,12 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 
 	ctx := r.Context()
 
+	keys, err := app.store.Attachments.Keys(ctx, id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err = app.store.Posts.Delete(ctx, id); err != nil {
 		switch {
 		case errors.Is(err, store.ErrorNotFound):
@@ -145,6 +258,9 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.deleteBlobs(keys...)
+	app.removeFromTimelines(getPostFromCtx(r))
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -169,11 +285,58 @@ func (app *application) postMiddlewareHandler(next http.Handler) http.Handler {
 			}
 			return
 		}
+		visible, err := app.canSeePost(ctx, post, getUserFromContext(r))
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		// posts the user may not see do not exist for them
+		if !visible {
+			app.notFoundResponse(w, r, store.ErrorNotFound)
+			return
+		}
+
 		ctx = context.WithValue(ctx, postCtx, post)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+func (app *application) canSeePost(ctx context.Context, post *store.Post, user *store.User) (bool, error) {
+	following := false
+	if post.Visibility == store.PostVisibilityFollowers && post.UserID != user.ID {
+		var err error
+		following, err = app.store.Followers.IsFollowing(ctx, user.ID, post.UserID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return post.VisibleTo(user.ID, following), nil
+}
+
+// setPublishAt checks the publish time against the status of the post. Only
+// scheduled posts have one, and it has to be in the future.
+func setPublishAt(post *store.Post, publishAt *time.Time) error {
+	if post.Status != store.PostStatusScheduled {
+		post.PublishAt = nil
+		return nil
+	}
+
+	if publishAt == nil {
+		return errors.New("scheduled posts need a publish_at")
+	}
+
+	if !publishAt.After(time.Now()) {
+		return errors.New("publish_at must be in the future")
+	}
+
+	t := publishAt.UTC().Format(time.RFC3339)
+	post.PublishAt = &t
+
+	return nil
+}
+
 func getPostFromCtx(r *http.Request) *store.Post {
 	post := r.Context().Value(postCtx).(*store.Post)
 
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestUnpublishedPosts(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &store.MockPostStore{Posts: map[int64]*store.Post{
+		1: {ID: 1, UserID: 1, Status: store.PostStatusDraft, Visibility: store.PostVisibilityPublic},
+		2: {ID: 2, UserID: 2, Status: store.PostStatusDraft, Visibility: store.PostVisibilityPublic},
+		3: {ID: 3, UserID: 2, Status: store.PostStatusScheduled, Visibility: store.PostVisibilityPublic},
+	}}
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+	}{
+		{"draft of another user", http.MethodGet, "/v1/posts/2", http.StatusNotFound},
+		{"scheduled post of another user", http.MethodGet, "/v1/posts/3", http.StatusNotFound},
+		{"reposting a draft of another user", http.MethodPost, "/v1/posts/2/repost", http.StatusNotFound},
+		{"reposting a scheduled post of another user", http.MethodPost, "/v1/posts/3/repost", http.StatusNotFound},
+		{"reposting an own draft", http.MethodPost, "/v1/posts/1/repost", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
+
+// visibilityPosts are posts of users 2 and 3, and of user 1 who follows user 3
+// but not user 2.
+func visibilityPosts() map[int64]*store.Post {
+	return map[int64]*store.Post{
+		1: {ID: 1, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPublic},
+		2: {ID: 2, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityUnlisted},
+		3: {ID: 3, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityFollowers},
+		4: {ID: 4, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPrivate},
+		5: {ID: 5, UserID: 3, Status: store.PostStatusPublished, Visibility: store.PostVisibilityFollowers},
+		6: {ID: 6, UserID: 1, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPrivate},
+	}
+}
+
+// visibilityTests are the responses to user 1 for the visibilityPosts, which
+// are only found when they may be seen.
+var visibilityTests = []struct {
+	name string
+	id   int64
+	code int
+}{
+	{"public", 1, http.StatusNoContent},
+	{"unlisted", 2, http.StatusNoContent},
+	{"followers only, not following", 3, http.StatusNotFound},
+	{"private", 4, http.StatusNotFound},
+	{"followers only, following", 5, http.StatusNoContent},
+	{"own private", 6, http.StatusNoContent},
+}
+
+func TestPostMiddlewareVisibility(t *testing.T) {
+	app := newTestApplication(t)
+	posts := visibilityPosts()
+	app.store.Posts = &store.MockPostStore{Posts: posts}
+	app.store.Followers = &store.MockFollowerStore{Following: map[int64][]int64{1: {3}}}
+
+	handler := app.postMiddlewareHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, tt := range visibilityTests {
+		t.Run(tt.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", strconv.FormatInt(tt.id, 10))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/posts/"+strconv.FormatInt(tt.id, 10), nil)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = context.WithValue(ctx, userCtx, &store.User{ID: 1})
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req.WithContext(ctx))
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
+
+func TestCommentMiddlewareVisibility(t *testing.T) {
+	app := newTestApplication(t)
+	posts := visibilityPosts()
+	app.store.Posts = &store.MockPostStore{Posts: posts}
+	app.store.Followers = &store.MockFollowerStore{Following: map[int64][]int64{1: {3}}}
+
+	// a comment by the viewer on each post
+	comments := map[int64]*store.Comment{}
+	for id := range posts {
+		comments[id] = &store.Comment{ID: id, PostID: id, UserID: 1}
+	}
+	app.store.Comments = &store.MockCommentStore{Comments: comments}
+
+	handler := app.commentMiddlewareHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, tt := range visibilityTests {
+		t.Run(tt.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("commentID", strconv.FormatInt(tt.id, 10))
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/comments/"+strconv.FormatInt(tt.id, 10), nil)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = context.WithValue(ctx, userCtx, &store.User{ID: 1})
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req.WithContext(ctx))
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/umeh-promise/social/internal/ranking"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+// RankedPost is a post of the ranked feed. Ranking explains its rank, for
+// users allowed to debug the feed who asked for it.
+type RankedPost struct {
+	store.PostWithMetadata
+	Ranking *PostRanking `json:"ranking,omitempty"`
+}
+
+type PostRanking struct {
+	Score      float64            `json:"score"`
+	Components map[string]float64 `json:"components"`
+}
+
+type RankedPostPage struct {
+	Posts      []RankedPost `json:"posts"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	PrevCursor string       `json:"prev_cursor,omitempty"`
+}
+
+// rankedFeed serves a page of the feed of the user ranked by relevance. The
+// feed is ranked as of the time of its first page, carried over by the
+// cursors: posts, comments and reactions made since are left out, so paging
+// through it does not reshuffle it. Ranks can still shift when posts,
+// comments, reactions or follows are removed in the meantime, as those are
+// gone from the ranking too.
+func (app *application) rankedFeed(w http.ResponseWriter, r *http.Request, user *store.User, fq store.PaginatedFeedQuery) {
+	debug := false
+	if param := r.URL.Query().Get("debug"); param != "" {
+		var err error
+		if debug, err = strconv.ParseBool(param); err != nil {
+			app.badRequestResponse(w, r, errors.New("invalid debug"))
+			return
+		}
+	}
+
+	if debug && !user.Role.HasPermission(store.PermissionFeedDebug) {
+		app.forbiddenResponseError(w, r)
+		return
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	offset := 0
+	if fq.Cursor != nil {
+		t, err := time.Parse(time.RFC3339Nano, fq.Cursor.CreatedAt)
+		if err != nil {
+			app.badRequestResponse(w, r, store.ErrorInvalidCursor)
+			return
+		}
+
+		at, offset = t, int(fq.Cursor.ID)
+	}
+
+	ctx := r.Context()
+
+	ranked, posts, err := app.rankFeed(ctx, user.ID, fq, at)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	start := min(offset, len(ranked))
+	end := min(offset+fq.Limit, len(ranked))
+
+	page := RankedPostPage{Posts: make([]RankedPost, 0, end-start)}
+	details := make([]*store.Post, 0, end-start)
+	for _, rp := range ranked[start:end] {
+		post := RankedPost{PostWithMetadata: posts[rp.PostID]}
+		if debug {
+			post.Ranking = &PostRanking{Score: rp.Score, Components: rp.Components}
+		}
+		page.Posts = append(page.Posts, post)
+	}
+	for i := range page.Posts {
+		details = append(details, &page.Posts[i].Post)
+	}
+
+	if err := app.store.Posts.AttachDetails(ctx, details, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	cursor := func(offset int) *store.Cursor {
+		return &store.Cursor{CreatedAt: at.Format(time.RFC3339), ID: int64(offset), Sort: fq.Sort, Ranked: true}
+	}
+
+	var next, prev *store.Cursor
+	if end < len(ranked) {
+		next = cursor(end)
+	}
+	if start > 0 {
+		prev = cursor(max(start-fq.Limit, 0))
+	}
+	page.NextCursor, page.PrevCursor = app.pageCursors(w, r, next, prev)
+
+	if err := app.jsonResponse(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// rankFeed ranks the recent posts of the feed of the user as of the given
+// time, and returns them by id along with the ranking.
+func (app *application) rankFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery, at time.Time) ([]ranking.Ranked, map[int64]store.PostWithMetadata, error) {
+	// only the posts of the window before the ranking are candidates
+	since := at.Add(-app.config.feed.rankingWindow).Format(time.RFC3339)
+	if fq.Since < since {
+		fq.Since = since
+	}
+	until := at.Add(time.Second).Format(time.RFC3339)
+	if fq.Until == "" || fq.Until > until {
+		fq.Until = until
+	}
+
+	feed, err := app.store.Posts.GetFeedCandidates(ctx, userID, fq, app.config.feed.rankingCandidates, at)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	posts := make(map[int64]store.PostWithMetadata, len(feed))
+	authors := []int64{}
+	seen := make(map[int64]bool)
+	for _, post := range feed {
+		posts[post.ID] = post.PostWithMetadata
+		if !seen[post.UserID] {
+			seen[post.UserID] = true
+			authors = append(authors, post.UserID)
+		}
+	}
+
+	affinities, err := app.store.Posts.Affinities(ctx, userID, authors, at.Add(-app.config.feed.affinityWindow), at.Add(time.Second))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := make([]ranking.Candidate, len(feed))
+	for i, post := range feed {
+		createdAt, err := time.Parse(time.RFC3339, post.CreatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		affinity := affinities[post.UserID]
+		candidates[i] = ranking.Candidate{
+			PostID:        post.ID,
+			AuthorID:      post.UserID,
+			CreatedAt:     createdAt,
+			Comments:      post.Comments,
+			Reactions:     post.Reactions,
+			FollowsAuthor: affinity.Follows,
+			Interactions:  affinity.Interactions,
+		}
+	}
+
+	return app.ranker.Rank(candidates, at), posts, nil
+}
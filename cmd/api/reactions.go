@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func (app *application) setPostReactionHandler(w http.ResponseWriter, r *http.Request) {
+	app.setReaction(w, r, store.ReactionTargetPost, getPostFromCtx(r).ID)
+}
+
+func (app *application) removePostReactionHandler(w http.ResponseWriter, r *http.Request) {
+	app.removeReaction(w, r, store.ReactionTargetPost, getPostFromCtx(r).ID)
+}
+
+func (app *application) setCommentReactionHandler(w http.ResponseWriter, r *http.Request) {
+	app.setReaction(w, r, store.ReactionTargetComment, getCommentFromCtx(r).ID)
+}
+
+func (app *application) removeCommentReactionHandler(w http.ResponseWriter, r *http.Request) {
+	app.removeReaction(w, r, store.ReactionTargetComment, getCommentFromCtx(r).ID)
+}
+
+// setReaction is idempotent: reacting twice with the same kind is a no-op and
+// another kind replaces the previous one.
+func (app *application) setReaction(w http.ResponseWriter, r *http.Request, targetType string, targetID int64) {
+	kind := chi.URLParam(r, "kind")
+	if !store.IsReactionKind(kind) {
+		app.badRequestResponse(w, r, fmt.Errorf("unknown reaction %q", kind))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Reactions.Set(r.Context(), user.ID, targetType, targetID, kind); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) removeReaction(w http.ResponseWriter, r *http.Request, targetType string, targetID int64) {
+	kind := chi.URLParam(r, "kind")
+	if !store.IsReactionKind(kind) {
+		app.badRequestResponse(w, r, fmt.Errorf("unknown reaction %q", kind))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Reactions.Remove(r.Context(), user.ID, targetType, targetID, kind); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestReactions(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &store.MockPostStore{Posts: map[int64]*store.Post{
+		1: {ID: 1, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPublic},
+		2: {ID: 2, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPrivate},
+	}}
+	app.store.Comments = &store.MockCommentStore{Comments: map[int64]*store.Comment{
+		1: {ID: 1, PostID: 1, UserID: 2},
+		2: {ID: 2, PostID: 2, UserID: 2},
+	}}
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+	}{
+		{"reacting to a post", http.MethodPut, "/v1/posts/1/reactions/like", http.StatusNoContent},
+		{"removing a reaction from a post", http.MethodDelete, "/v1/posts/1/reactions/like", http.StatusNoContent},
+		{"unknown reaction", http.MethodPut, "/v1/posts/1/reactions/meh", http.StatusBadRequest},
+		{"reacting to a hidden post", http.MethodPut, "/v1/posts/2/reactions/like", http.StatusNotFound},
+		{"reacting to a comment", http.MethodPut, "/v1/comments/1/reactions/love", http.StatusNoContent},
+		{"removing a reaction from a comment", http.MethodDelete, "/v1/comments/1/reactions/love", http.StatusNoContent},
+		{"unknown reaction to a comment", http.MethodDelete, "/v1/comments/1/reactions/meh", http.StatusBadRequest},
+		{"reacting to a comment on a hidden post", http.MethodPut, "/v1/comments/2/reactions/like", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+type RepostPayload struct {
+	Quote string `json:"quote" validate:"max=1000"`
+}
+
+// repostHandler shares the post, as a quote when the payload has one. The body
+// is optional for plain reposts.
+func (app *application) repostHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RepostPayload
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	original := getPostFromCtx(r)
+
+	// sharing would widen the audience of anything but public posts
+	if original.Status != store.PostStatusPublished || original.Visibility != store.PostVisibilityPublic {
+		app.badRequestResponse(w, r, errors.New("only published public posts can be shared"))
+		return
+	}
+
+	post := &store.Post{
+		UserID:  user.ID,
+		Content: payload.Quote,
+		Tags:    []string{},
+		User: store.User{
+			ID:       user.ID,
+			Username: user.Username,
+		},
+	}
+
+	ctx := r.Context()
+
+	var err error
+	if payload.Quote != "" {
+		err = app.store.Posts.Quote(ctx, post, original)
+	} else {
+		err = app.store.Posts.Repost(ctx, post, original)
+	}
+	if err != nil {
+		switch err {
+		case store.ErrorConflict:
+			app.conflictResponse(w, r, errors.New("post already reposted"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.fanOutPost(post)
+
+	if err := app.store.Posts.AttachDetails(ctx, []*store.Post{post}, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deleteRepostHandler undoes a plain repost. Quotes are deleted like any other
+// post.
+func (app *application) deleteRepostHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	original := getPostFromCtx(r)
+
+	if err := app.store.Posts.DeleteRepost(r.Context(), user.ID, original); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
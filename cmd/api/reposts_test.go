@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestRepost(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = &store.MockPostStore{Posts: map[int64]*store.Post{
+		1: {ID: 1, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPublic},
+		2: {ID: 2, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityFollowers},
+		3: {ID: 3, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPrivate},
+		4: {ID: 4, UserID: 2, Status: store.PostStatusPublished, Visibility: store.PostVisibilityUnlisted},
+	}}
+	app.store.Followers = &store.MockFollowerStore{Following: map[int64][]int64{1: {2}}}
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"reposting", http.MethodPost, "/v1/posts/1/repost", "", http.StatusCreated},
+		{"quoting", http.MethodPost, "/v1/posts/1/repost", `{"quote":"so true"}`, http.StatusCreated},
+		{"quoting at length", http.MethodPost, "/v1/posts/1/repost", `{"quote":"` + strings.Repeat("a", 1001) + `"}`, http.StatusBadRequest},
+		{"reposting a followers only post", http.MethodPost, "/v1/posts/2/repost", "", http.StatusBadRequest},
+		{"reposting an unlisted post", http.MethodPost, "/v1/posts/4/repost", "", http.StatusBadRequest},
+		{"reposting a hidden post", http.MethodPost, "/v1/posts/3/repost", "", http.StatusNotFound},
+		{"undoing a repost", http.MethodDelete, "/v1/posts/1/repost", "", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+
+			req, err := http.NewRequest(tt.method, tt.path, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
+
+func TestQuoteOwnership(t *testing.T) {
+	app := newTestApplication(t)
+
+	originalID := int64(1)
+	quote := &store.Post{ID: 2, UserID: 2, Kind: store.PostKindQuote, OriginalPostID: &originalID}
+
+	tests := []struct {
+		name string
+		user *store.User
+		code int
+	}{
+		{"author", &store.User{ID: 2}, http.StatusNoContent},
+		{"author of the original", &store.User{ID: 1}, http.StatusForbidden},
+		{"moderator", &store.User{ID: 3, Role: store.Role{Permissions: []string{store.PermissionPostDeleteAny}}}, http.StatusNoContent},
+		{"moderator allowed to update only", &store.User{ID: 3, Role: store.Role{Permissions: []string{store.PermissionPostUpdateAny}}}, http.StatusForbidden},
+	}
+
+	handler := app.checkPostOwnership(store.PermissionPostDeleteAny, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/v1/posts/2", nil)
+			ctx := context.WithValue(req.Context(), userCtx, tt.user)
+			ctx = context.WithValue(ctx, postCtx, quote)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req.WithContext(ctx))
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
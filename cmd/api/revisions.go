@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/umeh-promise/social/internal/diff"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+// RevisionDiff is what an edit changed, from the revision to the next state
+// of the post.
+type RevisionDiff struct {
+	Title       []diff.Op `json:"title"`
+	Content     []diff.Op `json:"content"`
+	TagsAdded   []string  `json:"tags_added"`
+	TagsRemoved []string  `json:"tags_removed"`
+}
+
+type RevisionWithDiff struct {
+	store.PostRevision
+	Diff RevisionDiff `json:"diff"`
+}
+
+type RevisionPage struct {
+	Revisions  []RevisionWithDiff `json:"revisions"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	PrevCursor string             `json:"prev_cursor,omitempty"`
+}
+
+func (app *application) listRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	cq := store.CursorQuery{
+		Limit: 20,
+		Sort:  "desc",
+	}
+
+	cq, err := cq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+
+	revisions, err := app.store.Revisions.List(r.Context(), post.ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	revisions, next, prev := store.Paginate(revisions, cq, func(revision store.PostRevision) (string, int64) {
+		return revision.CreatedAt, revision.ID
+	})
+
+	page := RevisionPage{Revisions: []RevisionWithDiff{}}
+	page.NextCursor, page.PrevCursor = app.pageCursors(w, r, next, prev)
+
+	for _, revision := range revisions {
+		added, removed := diff.Sets(revision.Tags, revision.Next.Tags)
+
+		page.Revisions = append(page.Revisions, RevisionWithDiff{
+			PostRevision: revision,
+			Diff: RevisionDiff{
+				Title:       diff.Words(revision.Title, revision.Next.Title),
+				Content:     diff.Words(revision.Content, revision.Next.Content),
+				TagsAdded:   added,
+				TagsRemoved: removed,
+			},
+		})
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// restoreRevisionHandler puts an earlier revision back as a new edit, so the
+// state it replaces stays in the history.
+func (app *application) restoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+	ctx := r.Context()
+
+	revision, err := app.store.Revisions.GetByVersion(ctx, post.ID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	post.Title = revision.Title
+	post.Content = revision.Content
+	post.Tags = revision.Tags
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Posts.Update(ctx, post, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorEditConflict):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", postETag(post))
+
+	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
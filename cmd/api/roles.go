@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func (app *application) listRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := app.store.Roles.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, roles); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+type CreateRolePayload struct {
+	Name              string   `json:"name" validate:"required,max=255"`
+	Description       string   `json:"description" validate:"max=1000"`
+	RequiresTwoFactor bool     `json:"requires_two_factor"`
+	Permissions       []string `json:"permissions" validate:"unique,dive,required,max=100"`
+}
+
+func (app *application) createRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateRolePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// nobody can hand out more than they hold themselves
+	user := getUserFromContext(r)
+	if !hasPermissions(user, payload.Permissions) {
+		app.forbiddenResponseError(w, r)
+		return
+	}
+
+	role := &store.Role{
+		Name:              payload.Name,
+		Description:       payload.Description,
+		RequiresTwoFactor: payload.RequiresTwoFactor,
+		Permissions:       payload.Permissions,
+	}
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+
+	if err := app.store.Roles.Create(r.Context(), role); err != nil {
+		switch err {
+		case store.ErrorConflict:
+			app.conflictResponse(w, r, errors.New("a role with that name already exists"))
+		case store.ErrorUnknownPermission:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, role); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+type AssignRolePayload struct {
+	Role string `json:"role" validate:"required,max=255"`
+}
+
+func (app *application) assignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload AssignRolePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	actor := getUserFromContext(r)
+	if actor.ID == userID {
+		app.badRequestResponse(w, r, errors.New("you cannot change your own role"))
+		return
+	}
+
+	ctx := r.Context()
+
+	role, err := app.store.Roles.GetByName(ctx, payload.Role)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	target, err := app.store.Users.GetByID(ctx, userID)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// neither grant nor take away permissions the actor doesn't hold
+	if !hasPermissions(actor, role.Permissions) || !hasPermissions(actor, target.Role.Permissions) {
+		app.forbiddenResponseError(w, r)
+		return
+	}
+
+	if err := app.store.Users.SetRole(ctx, userID, role.Name); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.invalidateUser(ctx, userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func hasPermissions(user *store.User, permissions []string) bool {
+	for _, permission := range permissions {
+		if !user.Role.HasPermission(permission) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"testing"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestSessions(t *testing.T) {
+	app := newTestApplication(t)
+	revokedAt := "2024-01-01T00:00:00Z"
+	app.store.Sessions = &store.MockSessionStore{Sessions: map[string]*store.Session{
+		"test-session":                         {ID: "test-session", UserID: 1},
+		"00000000-0000-0000-0000-000000000001": {ID: "00000000-0000-0000-0000-000000000001", UserID: 1},
+		"00000000-0000-0000-0000-000000000002": {ID: "00000000-0000-0000-0000-000000000002", UserID: 1, RevokedAt: &revokedAt},
+		"00000000-0000-0000-0000-000000000003": {ID: "00000000-0000-0000-0000-000000000003", UserID: 2},
+	}}
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(t *testing.T, method, path string) *http.Request {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return req
+	}
+
+	t.Run("should list the live sessions of the user", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodGet, "/v1/users/me/sessions"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []store.Session `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		current := map[string]bool{}
+		for _, session := range body.Data {
+			current[session.ID] = session.Current
+		}
+		want := map[string]bool{"test-session": true, "00000000-0000-0000-0000-000000000001": false}
+		if !maps.Equal(current, want) {
+			t.Errorf("got sessions %v, expected %v", current, want)
+		}
+	})
+
+	t.Run("should revoke a session of the user", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodDelete, "/v1/users/me/sessions/00000000-0000-0000-0000-000000000001"), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("should not find sessions of other users", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodDelete, "/v1/users/me/sessions/00000000-0000-0000-0000-000000000003"), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should reject malformed session ids", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodDelete, "/v1/users/me/sessions/current"), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestAuthTokenMiddlewareSessions(t *testing.T) {
+	revokedAt := "2024-01-01T00:00:00Z"
+
+	tests := []struct {
+		name    string
+		session *store.Session
+		code    int
+	}{
+		{"live session", &store.Session{ID: "test-session", UserID: 1}, http.StatusOK},
+		{"deleted session", nil, http.StatusUnauthorized},
+		{"revoked session", &store.Session{ID: "test-session", UserID: 1, RevokedAt: &revokedAt}, http.StatusUnauthorized},
+		{"session of another user", &store.Session{ID: "test-session", UserID: 2}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			sessions := map[string]*store.Session{}
+			if tt.session != nil {
+				sessions[tt.session.ID] = tt.session
+			}
+			app.store.Sessions = &store.MockSessionStore{Sessions: sessions}
+			mux := app.mount()
+
+			testToken, err := app.authenticator.GenerateToken(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "/v1/users/me/sessions", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
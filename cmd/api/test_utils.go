@@ -4,9 +4,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/umeh-promise/social/internal/auth"
+	"github.com/umeh-promise/social/internal/ratelimiter"
 	"github.com/umeh-promise/social/internal/store"
 	"github.com/umeh-promise/social/internal/store/cache"
 	"go.uber.org/zap"
@@ -24,6 +26,9 @@ func newTestApplication(t testing.TB) *application {
 		store:         mockStore,
 		cacheStorage:  mockCacheStore,
 		authenticator: testAuth,
+
+		activationLimiter: ratelimiter.NewFixedWindowLimiter(3, time.Hour),
+		cursors:           store.NewCursorCodec([]byte("test")),
 	}
 }
 
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/umeh-promise/social/internal/store"
+	"github.com/umeh-promise/social/internal/store/cache"
+)
+
+// maxTimelineWindow bounds the posts read from a timeline for a page of the
+// feed, past it the page is read from the database.
+const maxTimelineWindow = 400
+
+// getUserFeed reads the feed from the user's cached timeline when timelines
+// are enabled, building it if need be, and from the database otherwise. The
+// timeline only orders posts newest first and is not filtered, other queries
+// go to the database.
+func (app *application) getUserFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	if !app.config.cache.enabled || fq.Sort != "desc" || fq.Search != "" || len(fq.Tags) > 0 ||
+		(fq.Cursor != nil && fq.Cursor.Prev) {
+		return app.store.Posts.GetUserFeed(ctx, userID, fq)
+	}
+
+	since, until, err := timelineBounds(fq)
+	if err != nil {
+		return nil, err
+	}
+
+	built := false
+	for count := 2 * (fq.Limit + 1); count <= maxTimelineWindow; {
+		window, err := app.cacheStorage.Timelines.Range(ctx, userID, since, until, count)
+		if err != nil {
+			return nil, err
+		}
+
+		if window == nil {
+			if built {
+				break
+			}
+			if err := app.buildTimeline(ctx, userID); err != nil {
+				return nil, err
+			}
+			built = true
+			continue
+		}
+
+		// posts older than the window may be missing from it, so only the ones
+		// newer than its oldest can be served
+		bounded := window.Full || window.Truncated
+		if bounded && len(window.PostIDs) == 0 {
+			break
+		}
+
+		var after *time.Time
+		if bounded {
+			after = &window.Oldest
+		}
+
+		feed, err := app.store.Posts.GetTimelineFeed(ctx, userID, window.PostIDs, app.config.feed.maxFanOutFollowers, after, fq)
+		if err != nil {
+			return nil, err
+		}
+
+		// the page is complete, or the timeline has nothing more to offer
+		if len(feed) > fq.Limit || !bounded {
+			return feed, nil
+		}
+
+		// the rest of a trimmed timeline is only in the database
+		if !window.Full {
+			break
+		}
+
+		count *= 2
+	}
+
+	return app.store.Posts.GetUserFeed(ctx, userID, fq)
+}
+
+// timelineBounds returns the range of creation times, both inclusive, a page
+// of the feed is in.
+func timelineBounds(fq store.PaginatedFeedQuery) (since, until time.Time, err error) {
+	if fq.Since != "" {
+		if since, err = time.Parse(time.RFC3339, fq.Since); err != nil {
+			return since, until, err
+		}
+	}
+
+	if fq.Until != "" {
+		if until, err = time.Parse(time.RFC3339, fq.Until); err != nil {
+			return since, until, err
+		}
+	}
+
+	if fq.Cursor != nil {
+		at, err := time.Parse(time.RFC3339Nano, fq.Cursor.CreatedAt)
+		if err != nil {
+			return since, until, err
+		}
+
+		if until.IsZero() || at.Before(until) {
+			until = at
+		}
+	}
+
+	return since, until, nil
+}
+
+// buildTimeline caches the timeline of the user from the database.
+func (app *application) buildTimeline(ctx context.Context, userID int64) error {
+	entries, err := app.store.Posts.TimelineEntries(ctx, userID, cache.TimelineLength)
+	if err != nil {
+		return err
+	}
+
+	return app.cacheStorage.Timelines.Build(ctx, userID, entries)
+}
+
+// fanOutFollowers returns the followers the posts of an author are pushed to,
+// and false when the author has more than maxFanOutFollowers followers, who
+// read their posts from the database instead.
+func (app *application) fanOutFollowers(ctx context.Context, authorID int64) ([]int64, bool, error) {
+	maxFollowers := app.config.feed.maxFanOutFollowers
+
+	followers, err := app.store.Followers.ListFollowerIDs(ctx, authorID, maxFollowers+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(followers) > maxFollowers {
+		return nil, false, nil
+	}
+
+	return followers, true, nil
+}
+
+// fanOutPost pushes a published post to the timelines of its author and of
+// their followers in the background.
+func (app *application) fanOutPost(post *store.Post) {
+	app.updateTimelines("error pushing post to timelines", post, app.cacheStorage.Timelines.Push)
+}
+
+// removeFromTimelines takes a deleted post out of the timelines it was pushed
+// to in the background. Timelines may still hold the ids of deleted posts,
+// they are left out when the posts are read.
+func (app *application) removeFromTimelines(post *store.Post) {
+	app.updateTimelines("error removing post from timelines", post, app.cacheStorage.Timelines.Remove)
+}
+
+func (app *application) updateTimelines(message string, post *store.Post, update func(context.Context, []int64, ...store.TimelineEntry) error) {
+	if !app.config.cache.enabled || post.Status != store.PostStatusPublished {
+		return
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, post.CreatedAt)
+	if err != nil {
+		app.logger.Errorw(message, "post", post.ID, "error", err)
+		return
+	}
+	entry := store.TimelineEntry{PostID: post.ID, UserID: post.UserID, CreatedAt: createdAt}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		followers, _, err := app.fanOutFollowers(ctx, post.UserID)
+		if err == nil {
+			err = update(ctx, append(followers, post.UserID), entry)
+		}
+		if err != nil {
+			app.logger.Errorw(message, "post", post.ID, "error", err)
+		}
+	}()
+}
+
+// backfillTimeline adds the posts of a newly followed user to the follower's
+// timeline in the background, unless they are read from the database.
+func (app *application) backfillTimeline(followerID, authorID int64) {
+	if !app.config.cache.enabled {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		err := func() error {
+			_, fansOut, err := app.fanOutFollowers(ctx, authorID)
+			if err != nil || !fansOut {
+				return err
+			}
+
+			entries, err := app.store.Posts.AuthorEntries(ctx, authorID, cache.TimelineLength)
+			if err != nil {
+				return err
+			}
+
+			return app.cacheStorage.Timelines.Push(ctx, []int64{followerID}, entries...)
+		}()
+		if err != nil {
+			app.logger.Errorw("error backfilling timeline", "user", followerID, "author", authorID, "error", err)
+		}
+	}()
+}
+
+// pruneTimeline takes the posts of an unfollowed user out of the follower's
+// timeline in the background.
+func (app *application) pruneTimeline(followerID, authorID int64) {
+	if !app.config.cache.enabled {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err := app.cacheStorage.Timelines.RemoveAuthor(ctx, followerID, authorID); err != nil {
+			app.logger.Errorw("error pruning timeline", "user", followerID, "author", authorID, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/umeh-promise/social/internal/auth"
+	"github.com/umeh-promise/social/internal/mailer"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+const (
+	accessTokenType    = "access"
+	challengeTokenType = "2fa_challenge"
+	recoveryCodeCount  = 10
+)
+
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+func (app *application) enrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// the secret stays pending until a code generated from it is verified
+	if err := app.store.TwoFactor.SetSecret(r.Context(), user.ID, secret); err != nil {
+		switch err {
+		case store.ErrorConflict:
+			app.conflictResponse(w, r, fmt.Errorf("two-factor authentication is already enabled"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	enrollment := TwoFactorEnrollment{
+		Secret: secret,
+		URI:    auth.TOTPURI(mailer.FromName, user.Email, secret),
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, enrollment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+type TwoFactorCodePayload struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+func (app *application) verifyTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var payload TwoFactorCodePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	tf, err := app.store.TwoFactor.GetByUserID(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if tf.Enabled {
+		app.conflictResponse(w, r, fmt.Errorf("two-factor authentication is already enabled"))
+		return
+	}
+	if tf.Secret == "" {
+		app.badRequestResponse(w, r, fmt.Errorf("two-factor enrollment has not been started"))
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(tf.Secret, payload.Code, time.Now())
+	if !ok {
+		app.badRequestResponse(w, r, store.ErrorInvalidCode)
+		return
+	}
+
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.TwoFactor.Enable(ctx, user.ID, step, codes); err != nil {
+		switch err {
+		case store.ErrorConflict:
+			app.conflictResponse(w, r, fmt.Errorf("two-factor authentication is already enabled"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.invalidateUser(ctx, user.ID)
+
+	// recovery codes are only ever shown in this response
+	response := struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) disableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var payload TwoFactorCodePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	if user.Role.RequiresTwoFactor {
+		app.forbiddenResponseError(w, r)
+		return
+	}
+
+	if err := app.verifySecondFactor(ctx, user.ID, payload.Code); err != nil {
+		switch err {
+		case store.ErrorInvalidCode:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.TwoFactor.Disable(ctx, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.invalidateUser(ctx, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+// newTwoFactorChallenge issues the short-lived token a client trades, together
+// with a code, for a session once the password has been verified.
+func (app *application) newTwoFactorChallenge(user *store.User) (*TwoFactorChallenge, error) {
+	exp := app.config.auth.token.challengeExp
+
+	claims := jwt.MapClaims{
+		"sub": user.ID,
+		"typ": challengeTokenType,
+		"exp": time.Now().Add(exp).Unix(),
+		"iat": time.Now().Unix(),
+		"nbf": time.Now().Unix(),
+		"iss": app.config.auth.token.issuer,
+		"aud": app.config.auth.token.issuer,
+	}
+	token, err := app.authenticator.GenerateToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int64(exp.Seconds()),
+	}, nil
+}
+
+type TwoFactorLoginPayload struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,max=32"`
+}
+
+func (app *application) createTwoFactorTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload TwoFactorLoginPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	jwtToken, err := app.authenticator.ValidateToken(payload.ChallengeToken)
+	if err != nil {
+		app.unathorizedErrorResponse(w, r, err)
+		return
+	}
+
+	claims := jwtToken.Claims.(jwt.MapClaims)
+	if claims["typ"] != challengeTokenType {
+		app.unathorizedErrorResponse(w, r, fmt.Errorf("invalid challenge token"))
+		return
+	}
+
+	userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
+	if err != nil {
+		app.unathorizedErrorResponse(w, r, err)
+		return
+	}
+
+	// a 6 digit code space is small, cap the guesses per account
+	if allow, retryAfter := app.twoFactorLimiter.Allow(strconv.FormatInt(userID, 10)); !allow {
+		app.rateLimitExceededResponse(w, r, retryAfter.String())
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.verifySecondFactor(ctx, userID, payload.Code); err != nil {
+		switch err {
+		case store.ErrorInvalidCode:
+			app.unathorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	tokens, err := app.createSession(r, &store.User{ID: userID})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code, and burns whichever was used.
+func (app *application) verifySecondFactor(ctx context.Context, userID int64, code string) error {
+	tf, err := app.store.TwoFactor.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !tf.Enabled {
+		return store.ErrorInvalidCode
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := auth.ValidateTOTP(tf.Secret, code, time.Now()); ok {
+		return app.store.TwoFactor.UseStep(ctx, userID, step)
+	}
+
+	return app.store.TwoFactor.UseRecoveryCode(ctx, userID, normalizeRecoveryCode(code))
+}
+
+// TwoFactorEnrollmentMiddleware blocks users whose role requires two-factor
+// authentication until they have enrolled.
+func (app *application) TwoFactorEnrollmentMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+
+		if user.Role.RequiresTwoFactor && !user.TwoFactorEnabled {
+			app.forbiddenResponseError(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns codes formatted as xxxxx-xxxxx.
+func generateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, " ", ""))
+	code = strings.ReplaceAll(code, "-", "")
+	if len(code) != 10 {
+		return code
+	}
+
+	return code[:5] + "-" + code[5:]
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+func TestTwoFactorEnrollmentMiddleware(t *testing.T) {
+	app := newTestApplication(t)
+
+	handler := app.TwoFactorEnrollmentMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name string
+		user *store.User
+		code int
+	}{
+		{"role without two factor", &store.User{}, http.StatusNoContent},
+		{"enrolled", &store.User{TwoFactorEnabled: true, Role: store.Role{RequiresTwoFactor: true}}, http.StatusNoContent},
+		{"not enrolled", &store.User{Role: store.Role{RequiresTwoFactor: true}}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/me/api-keys", nil)
+			req = req.WithContext(context.WithValue(req.Context(), userCtx, tt.user))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
@@ -12,6 +12,31 @@ type Userkey string
 
 const userCtx Userkey = "user"
 
+// UserProfile is a user as anyone can see them, without the security settings
+// of the account. Only the name of their role is shown, its permissions would
+// tell who can moderate what.
+type UserProfile struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+	IsActive  bool   `json:"is_active"`
+	RoleID    int64  `json:"role_id"`
+	Role      string `json:"role"`
+}
+
+func newUserProfile(user *store.User) UserProfile {
+	return UserProfile{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+		IsActive:  user.IsActive,
+		RoleID:    user.RoleID,
+		Role:      user.Role.Name,
+	}
+}
+
 // GetUser godoc
 //
 //	@Summary		Fetches a user profile
@@ -20,7 +45,7 @@ const userCtx Userkey = "user"
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		int	true	"User ID"
-//	@Success		200	{object}	store.User
+//	@Success		200	{object}	UserProfile
 //	@Failure		400	{object}	error
 //	@Failure		404	{object}	error
 //	@Failure		500	{object}	error
@@ -47,7 +72,7 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, newUserProfile(user)); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -58,6 +83,7 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 	followedUserID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
+		return
 	}
 
 	ctx := r.Context()
@@ -72,6 +98,8 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.backfillTimeline(user.ID, followedUserID)
+
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -83,6 +111,7 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 	unfollowedUserID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
+		return
 	}
 
 	ctx := r.Context()
@@ -91,6 +120,8 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.pruneTimeline(user.ID, unfollowedUserID)
+
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -33,4 +34,32 @@ func TestGetUser(t *testing.T) {
 		rr := executeRequest(req, mux)
 		checkResponseCode(t, http.StatusOK, rr.Code)
 	})
+
+	t.Run("should only show the name of the role", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if strings.Contains(rr.Body.String(), "permissions") {
+			t.Errorf("expected no permissions, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("should not tell whether the user has a second factor", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if strings.Contains(rr.Body.String(), "two_factor_enabled") {
+			t.Errorf("expected no two factor status, got %s", rr.Body.String())
+		}
+	})
 }
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN totp_secret varchar(64),
+    ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN totp_last_step bigint;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    code bytea PRIMARY KEY,
+    user_id bigint NOT NULL,
+    used_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE roles
+DROP COLUMN requires_two_factor;
\ No newline at end of file
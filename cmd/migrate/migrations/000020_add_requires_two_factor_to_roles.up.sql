@@ -0,0 +1,6 @@
+ALTER TABLE roles
+ADD COLUMN requires_two_factor BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE roles
+SET requires_two_factor = TRUE
+WHERE name IN ('moderator', 'admin');
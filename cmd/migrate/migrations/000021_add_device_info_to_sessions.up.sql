@@ -0,0 +1,4 @@
+ALTER TABLE sessions
+    ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ip varchar(64) NOT NULL DEFAULT '',
+    ADD COLUMN last_seen_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
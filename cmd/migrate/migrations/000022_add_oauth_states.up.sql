@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS oauth_states (
+    state bytea PRIMARY KEY,
+    provider varchar(50) NOT NULL,
+    nonce text NOT NULL,
+    code_verifier text NOT NULL,
+    expiry timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    id bigserial PRIMARY KEY,
+    name varchar(100) NOT NULL UNIQUE,
+    description TEXT
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id bigint NOT NULL,
+    permission_id bigint NOT NULL,
+
+    PRIMARY KEY (role_id, permission_id),
+    FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE
+);
+
+INSERT INTO permissions (name, description)
+VALUES
+    ('post.update.any', 'Update posts of other users'),
+    ('post.delete.any', 'Delete posts of other users'),
+    ('comment.update.any', 'Update comments of other users'),
+    ('comment.delete.any', 'Delete comments of other users'),
+    ('user.ban', 'Ban users'),
+    ('role.create', 'Create roles'),
+    ('role.assign', 'Assign roles to users');
+
+-- carry over what the role levels used to allow
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'moderator' AND p.name IN ('post.update.any', 'comment.update.any', 'comment.delete.any');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'admin';
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_comments_parent_id;
+
+ALTER TABLE comments
+DROP COLUMN IF EXISTS updated_at;
+
+ALTER TABLE comments
+DROP COLUMN IF EXISTS parent_id;
+
+ALTER TABLE comments
+DROP CONSTRAINT IF EXISTS fk_comments_post;
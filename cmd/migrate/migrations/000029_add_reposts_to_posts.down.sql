@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_posts_unique_repost;
+
+DROP INDEX IF EXISTS idx_posts_original_post_id;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS original_post_id;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,11 @@
+ALTER TABLE posts
+ADD COLUMN kind varchar(10) NOT NULL DEFAULT 'post';
+
+-- quotes outlive the post they quote, the reference is cleared instead
+ALTER TABLE posts
+ADD COLUMN original_post_id bigint REFERENCES posts (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_original_post_id ON posts (original_post_id);
+
+-- a post can only be reposted once by the same user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_unique_repost ON posts (user_id, original_post_id) WHERE kind = 'repost';
@@ -0,0 +1,6 @@
+DELETE FROM permissions WHERE name = 'post.restore';
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS edited_at;
+
+DROP TABLE IF EXISTS post_revisions;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL,
+    version int NOT NULL,
+    title text NOT NULL,
+    content text NOT NULL,
+    tags varchar(100) [],
+    edited_by bigint,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    UNIQUE (post_id, version),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (edited_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+ALTER TABLE posts
+ADD COLUMN edited_at timestamp(0) with time zone;
+
+INSERT INTO permissions (name, description)
+VALUES ('post.restore', 'Restore earlier revisions of posts');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name IN ('moderator', 'admin') AND p.name = 'post.restore';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_posts_scheduled;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS publish_at,
+DROP COLUMN IF EXISTS status;
@@ -0,0 +1,2 @@
+ALTER TABLE posts
+ADD COLUMN visibility varchar(20) NOT NULL DEFAULT 'public';
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS polls (
+    post_id bigint PRIMARY KEY,
+    multiple boolean NOT NULL DEFAULT false,
+    closes_at timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL,
+    position int NOT NULL,
+    text varchar(100) NOT NULL,
+
+    UNIQUE (post_id, position),
+    UNIQUE (id, post_id),
+    FOREIGN KEY (post_id) REFERENCES polls (post_id) ON DELETE CASCADE
+);
+
+-- a ballot per user and poll, so nobody votes twice
+CREATE TABLE IF NOT EXISTS poll_ballots (
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (post_id, user_id),
+    FOREIGN KEY (post_id) REFERENCES polls (post_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- the options chosen on a ballot, which have to belong to its poll
+CREATE TABLE IF NOT EXISTS poll_votes (
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    option_id bigint NOT NULL,
+
+    PRIMARY KEY (post_id, user_id, option_id),
+    FOREIGN KEY (post_id, user_id) REFERENCES poll_ballots (post_id, user_id) ON DELETE CASCADE,
+    FOREIGN KEY (option_id, post_id) REFERENCES poll_options (id, post_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_votes_option_id ON poll_votes (option_id);
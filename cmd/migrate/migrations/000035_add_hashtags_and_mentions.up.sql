@@ -0,0 +1,59 @@
+CREATE TABLE IF NOT EXISTS hashtags (
+    id bigserial PRIMARY KEY,
+    name varchar(100) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS post_hashtags (
+    post_id bigint NOT NULL,
+    hashtag_id bigint NOT NULL,
+
+    PRIMARY KEY (post_id, hashtag_id),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (hashtag_id) REFERENCES hashtags (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_hashtags_hashtag_id ON post_hashtags (hashtag_id, post_id);
+
+CREATE TABLE IF NOT EXISTS post_mentions (
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+
+    PRIMARY KEY (post_id, user_id),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_mentions_user_id ON post_mentions (user_id, post_id);
+
+-- backfill existing posts from their tags and content, approximating the
+-- parser of internal/markup
+WITH found AS (
+    SELECT p.id AS post_id, lower(t) AS name
+    FROM posts p, unnest(p.tags) t
+    WHERE t ~ '^#?[[:alnum:]_]*[[:alpha:]][[:alnum:]_]*$'
+    UNION
+    SELECT p.id, lower(m[2])
+    FROM posts p, regexp_matches(p.content, '(^|[^[:alnum:]_@#])#([[:alnum:]_]*[[:alpha:]][[:alnum:]_]*)', 'g') m
+)
+INSERT INTO hashtags (name)
+SELECT DISTINCT ltrim(name, '#') FROM found WHERE length(ltrim(name, '#')) <= 100
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO post_hashtags (post_id, hashtag_id)
+SELECT DISTINCT f.post_id, h.id
+FROM (
+    SELECT p.id AS post_id, ltrim(lower(t), '#') AS name
+    FROM posts p, unnest(p.tags) t
+    UNION
+    SELECT p.id, lower(m[2])
+    FROM posts p, regexp_matches(p.content, '(^|[^[:alnum:]_@#])#([[:alnum:]_]*[[:alpha:]][[:alnum:]_]*)', 'g') m
+) f
+JOIN hashtags h ON h.name = f.name
+ON CONFLICT DO NOTHING;
+
+INSERT INTO post_mentions (post_id, user_id)
+SELECT DISTINCT p.id, u.id
+FROM posts p
+CROSS JOIN LATERAL regexp_matches(p.content, '(^|[^[:alnum:]_@#])@([A-Za-z0-9_.]*[A-Za-z0-9_])', 'g') m
+JOIN users u ON lower(u.username) = lower(m[2])
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+INSERT INTO permissions (name, description)
+VALUES ('feed.debug', 'See how ranked feeds are scored');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.name = 'feed.debug';
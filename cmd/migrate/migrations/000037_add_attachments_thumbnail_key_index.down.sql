@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_attachments_thumbnail_key;
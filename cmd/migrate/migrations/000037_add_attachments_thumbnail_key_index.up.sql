@@ -0,0 +1,2 @@
+-- media requests look up the post of thumbnails too
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attachments_thumbnail_key ON attachments (thumbnail_key);
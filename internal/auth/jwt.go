@@ -7,18 +7,25 @@ import (
 )
 
 type JWTAuthenticator struct {
-	secret string
+	keys   *KeyRing
 	aud    string
 	issuer string
 }
 
-func NewJWTAuthenticator(secret, aud, issuer string) *JWTAuthenticator {
-	return &JWTAuthenticator{secret: secret, aud: aud, issuer: issuer}
+func NewJWTAuthenticator(keys *KeyRing, aud, issuer string) *JWTAuthenticator {
+	return &JWTAuthenticator{keys: keys, aud: aud, issuer: issuer}
 }
 
 func (auth *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(auth.secret))
+	key, err := auth.keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -28,15 +35,25 @@ func (auth *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
 
 func (auth *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 	return jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := auth.keys.Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		if t.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
 		}
 
-		return []byte(auth.secret), nil
+		return key.Public(), nil
 	},
 		jwt.WithExpirationRequired(),
 		jwt.WithAudience(auth.aud),
-		jwt.WithIssuer(auth.aud),
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		jwt.WithIssuer(auth.issuer),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name, jwt.SigningMethodEdDSA.Alg()}),
 	)
 }
+
+func (auth *JWTAuthenticator) JWKS() JWKSet {
+	return auth.keys.JWKS()
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": 1,
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iss": "test",
+		"aud": "test",
+	}
+}
+
+func newEd25519Key(t testing.TB, id string, activeAt time.Time) *SigningKey {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := newSigningKey(id, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.ActiveAt = activeAt
+
+	return key
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := newEd25519Key(t, "old", time.Now().Add(-time.Hour))
+	ring := NewKeyRing(oldKey)
+	authenticator := NewJWTAuthenticator(ring, "test", "test")
+
+	oldToken, err := authenticator.GenerateToken(newTestClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not sign with a key before it is active", func(t *testing.T) {
+		ring.keys["new"] = newEd25519Key(t, "new", time.Now().Add(time.Hour))
+
+		key, err := ring.SigningKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.ID != "old" {
+			t.Errorf("expected signing key %q, got %q", "old", key.ID)
+		}
+
+		if len(ring.JWKS().Keys) != 2 {
+			t.Errorf("expected pending key to be published")
+		}
+	})
+
+	t.Run("should sign with the new key and still verify old tokens", func(t *testing.T) {
+		ring.keys["new"].ActiveAt = time.Now().Add(-time.Minute)
+
+		newToken, err := authenticator.GenerateToken(newTestClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := authenticator.ValidateToken(newToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Header["kid"] != "new" {
+			t.Errorf("expected kid %q, got %v", "new", parsed.Header["kid"])
+		}
+
+		if _, err := authenticator.ValidateToken(oldToken); err != nil {
+			t.Errorf("expected old token to verify, got %v", err)
+		}
+	})
+
+	t.Run("should reject tokens signed with removed keys", func(t *testing.T) {
+		delete(ring.keys, "old")
+
+		if _, err := authenticator.ValidateToken(oldToken); err == nil {
+			t.Error("expected token signed with removed key to be rejected")
+		}
+	})
+
+	t.Run("should reject HMAC tokens", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, newTestClaims())
+		token.Header["kid"] = "new"
+		signed, err := token.SignedString([]byte("basic"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := authenticator.ValidateToken(signed); err == nil {
+			t.Error("expected HS256 token to be rejected")
+		}
+	})
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	if err := os.WriteFile(filepath.Join(dir, "rsa-1.pem"), rsaPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "ed-1.pem"), edPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ring, err := LoadKeyRing(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := ring.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(set.Keys))
+	}
+
+	if set.Keys[0].KeyID != "ed-1" || set.Keys[0].Alg != "EdDSA" || set.Keys[0].KeyType != "OKP" {
+		t.Errorf("unexpected Ed25519 JWK %+v", set.Keys[0])
+	}
+	if set.Keys[1].KeyID != "rsa-1" || set.Keys[1].Alg != "RS256" || set.Keys[1].E != "AQAB" {
+		t.Errorf("unexpected RSA JWK %+v", set.Keys[1])
+	}
+
+	authenticator := NewJWTAuthenticator(ring, "test", "test")
+	token, err := authenticator.GenerateToken(newTestClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticator.ValidateToken(token); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrNoSigningKey = errors.New("no signing key available")
+
+// SigningKey is a private key used to sign tokens. Tokens carry the key ID in
+// their `kid` header so verifiers can pick the matching public key.
+type SigningKey struct {
+	ID       string
+	Method   jwt.SigningMethod
+	Private  crypto.Signer
+	ActiveAt time.Time
+}
+
+func (k *SigningKey) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// ParsePrivateKey reads a PEM encoded RSA (PKCS#1 or PKCS#8) or Ed25519
+// (PKCS#8) private key.
+func ParsePrivateKey(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM block found", id)
+	}
+
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %s: unsupported PEM block type %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+
+	return newSigningKey(id, key)
+}
+
+func newSigningKey(id string, key any) (*SigningKey, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("key %s: RSA keys must be at least 2048 bits", id)
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, Private: k}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, Private: k}, nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %T", id, key)
+	}
+}
+
+// KeyRing holds every key that may verify tokens and decides which one signs.
+//
+// Rotation works by adding a new key next to the current one: a key is
+// published in the JWKS as soon as it is loaded but only starts signing once
+// its ActiveAt time has passed, giving other services time to fetch it. Older
+// keys keep verifying until they are removed from the ring.
+type KeyRing struct {
+	sync.RWMutex
+	dir          string
+	publishDelay time.Duration
+	keys         map[string]*SigningKey
+}
+
+func NewKeyRing(keys ...*SigningKey) *KeyRing {
+	kr := &KeyRing{keys: make(map[string]*SigningKey)}
+	for _, key := range keys {
+		kr.keys[key.ID] = key
+	}
+
+	return kr
+}
+
+// NewEphemeralKeyRing generates a throwaway Ed25519 key, tokens signed with it
+// do not survive a restart. Meant for local development only.
+func NewEphemeralKeyRing() (*KeyRing, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := newSigningKey("ephemeral", private)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyRing(key), nil
+}
+
+// LoadKeyRing loads every *.pem file in dir. The file name (without extension)
+// is the key ID and a key becomes the signing key publishDelay after the file
+// was last modified.
+func LoadKeyRing(dir string, publishDelay time.Duration) (*KeyRing, error) {
+	kr := &KeyRing{dir: dir, publishDelay: publishDelay}
+	if err := kr.Reload(); err != nil {
+		return nil, err
+	}
+
+	return kr, nil
+}
+
+func (kr *KeyRing) Reload() error {
+	if kr.dir == "" {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(kr.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*SigningKey, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		key, err := ParsePrivateKey(id, data)
+		if err != nil {
+			return err
+		}
+		key.ActiveAt = info.ModTime().Add(kr.publishDelay)
+
+		keys[id] = key
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("no keys found in %s", kr.dir)
+	}
+
+	kr.Lock()
+	kr.keys = keys
+	kr.Unlock()
+
+	return nil
+}
+
+// Watch reloads the key directory every interval until ctx is done.
+func (kr *KeyRing) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	if kr.dir == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := kr.Reload(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// SigningKey returns the most recently activated key. When no key is active
+// yet (e.g. a fresh deployment) the one closest to activation is used.
+func (kr *KeyRing) SigningKey() (*SigningKey, error) {
+	kr.RLock()
+	defer kr.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(kr.keys))
+	for _, key := range kr.keys {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoSigningKey
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ActiveAt.Equal(keys[j].ActiveAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].ActiveAt.Before(keys[j].ActiveAt)
+	})
+
+	now := time.Now()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !keys[i].ActiveAt.After(now) {
+			return keys[i], nil
+		}
+	}
+
+	return keys[0], nil
+}
+
+func (kr *KeyRing) Key(id string) (*SigningKey, bool) {
+	kr.RLock()
+	defer kr.RUnlock()
+
+	key, ok := kr.keys[id]
+	return key, ok
+}
+
+// JWK is the public half of a signing key as described in RFC 7517.
+type JWK struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey decodes an RSA or Ed25519 JWK.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size %d", len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+func (kr *KeyRing) JWKS() JWKSet {
+	kr.RLock()
+	defer kr.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range kr.keys {
+		jwk := JWK{KeyID: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+		switch pub := key.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+
+	return set
+}
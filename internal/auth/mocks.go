@@ -14,6 +14,7 @@ var testClaims = jwt.MapClaims{
 	"aud":    "test-aud",
 	"issuer": "test-aud",
 	"sub":    int64(1),
+	"sid":    "test-session",
 	"exp":    time.Now().Add(time.Hour).Unix(),
 }
 
@@ -29,3 +30,7 @@ func (auth *TestAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 		return []byte(secret), nil
 	})
 }
+
+func (auth *TestAuthenticator) JWKS() JWKSet {
+	return JWKSet{Keys: []JWK{}}
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockOIDCServer is a minimal OpenID provider: it publishes discovery and
+// JWKS documents and redeems codes registered through authorize.
+type mockOIDCServer struct {
+	*httptest.Server
+	key *SigningKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCServer(t testing.TB) *mockOIDCServer {
+	t.Helper()
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := newSigningKey("mock-key", private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &mockOIDCServer{key: key, codes: make(map[string]mockAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                server.URL,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			JWKSURI:               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(NewKeyRing(key).JWKS())
+	})
+	mux.HandleFunc("/token", server.token)
+
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// authorize stands in for the user logging in at the provider.
+func (s *mockOIDCServer) authorize(t testing.TB, authURL string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := u.Query()
+	if params.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected S256 code challenge, got %q", params.Get("code_challenge_method"))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := "code-" + params.Get("state")
+	s.codes[code] = mockAuthorization{
+		challenge: params.Get("code_challenge"),
+		nonce:     params.Get("nonce"),
+		claims:    claims,
+	}
+
+	return code
+}
+
+func (s *mockOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	authz, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	if !ok || r.PostForm.Get("client_id") != "client" || r.PostForm.Get("client_secret") != "secret" {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	if PKCEChallenge(r.PostForm.Get("code_verifier")) != authz.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   s.URL,
+		"aud":   "client",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": authz.nonce,
+	}
+	for k, v := range authz.claims {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(s.key.Method, claims)
+	token.Header["kid"] = s.key.ID
+
+	idToken, err := token.SignedString(s.key.Private)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+func newTestOIDCProvider(t testing.TB, server *mockOIDCServer) *OIDCProvider {
+	t.Helper()
+
+	provider, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		Name:         "mock",
+		IssuerURL:    server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return provider
+}
+
+func TestOIDCProvider(t *testing.T) {
+	server := newMockOIDCServer(t)
+	provider := newTestOIDCProvider(t, server)
+	ctx := context.Background()
+
+	t.Run("should complete the authorization code flow", func(t *testing.T) {
+		verifier, challenge, err := NewPKCE()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code := server.authorize(t, provider.AuthCodeURL("state-1", "nonce-1", challenge), jwt.MapClaims{
+			"sub":            "user-1",
+			"email":          "jane@example.com",
+			"email_verified": true,
+		})
+
+		identity, err := provider.Exchange(ctx, code, verifier, "nonce-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if identity.Subject != "user-1" || identity.Email != "jane@example.com" || !identity.EmailVerified {
+			t.Errorf("unexpected identity %+v", identity)
+		}
+		if identity.Provider != "mock" {
+			t.Errorf("expected provider mock, got %q", identity.Provider)
+		}
+	})
+
+	t.Run("should reject a wrong code verifier", func(t *testing.T) {
+		_, challenge, err := NewPKCE()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code := server.authorize(t, provider.AuthCodeURL("state-2", "nonce-2", challenge), jwt.MapClaims{"sub": "user-1"})
+
+		if _, err := provider.Exchange(ctx, code, "not-the-verifier", "nonce-2"); err == nil {
+			t.Error("expected the exchange to fail")
+		}
+	})
+
+	t.Run("should reject a nonce mismatch", func(t *testing.T) {
+		verifier, challenge, err := NewPKCE()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code := server.authorize(t, provider.AuthCodeURL("state-3", "nonce-3", challenge), jwt.MapClaims{"sub": "user-1"})
+
+		if _, err := provider.Exchange(ctx, code, verifier, "another-nonce"); err != ErrInvalidNonce {
+			t.Errorf("expected ErrInvalidNonce, got %v", err)
+		}
+	})
+
+	t.Run("should reject tokens for another audience", func(t *testing.T) {
+		verifier, challenge, err := NewPKCE()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code := server.authorize(t, provider.AuthCodeURL("state-4", "nonce-4", challenge), jwt.MapClaims{
+			"sub": "user-1",
+			"aud": "someone-else",
+		})
+
+		if _, err := provider.Exchange(ctx, code, verifier, "nonce-4"); err == nil {
+			t.Error("expected the id token to be rejected")
+		}
+	})
+
+	t.Run("should treat a string email_verified claim as a boolean", func(t *testing.T) {
+		verifier, challenge, err := NewPKCE()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code := server.authorize(t, provider.AuthCodeURL("state-5", "nonce-5", challenge), jwt.MapClaims{
+			"sub":            "user-2",
+			"email":          "john@example.com",
+			"email_verified": "false",
+		})
+
+		identity, err := provider.Exchange(ctx, code, verifier, "nonce-5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if identity.EmailVerified {
+			t.Error("expected the email to be unverified")
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B test secret, truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, v := range vectors {
+		code, err := TOTPCode(secret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Errorf("at %d expected %s, got %s", v.unix, v.code, code)
+		}
+	}
+
+	t.Run("should accept codes from the adjacent period", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+		code, _ := TOTPCode(secret, now.Add(-totpPeriod*time.Second))
+
+		step, ok := ValidateTOTP(secret, code, now)
+		if !ok {
+			t.Fatal("expected code from previous period to be accepted")
+		}
+		if step != now.Unix()/totpPeriod-1 {
+			t.Errorf("unexpected step %d", step)
+		}
+	})
+
+	t.Run("should reject stale codes", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+		code, _ := TOTPCode(secret, now.Add(-5*totpPeriod*time.Second))
+
+		if _, ok := ValidateTOTP(secret, code, now); ok {
+			t.Error("expected stale code to be rejected")
+		}
+	})
+}
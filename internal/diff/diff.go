@@ -0,0 +1,101 @@
+// Package diff computes word level differences between two texts, small
+// enough to be rendered inline by the clients.
+package diff
+
+import (
+	"regexp"
+	"slices"
+)
+
+// Kinds of operations in a diff.
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// Op is a run of text that is kept, inserted or deleted. Applying the equal
+// and insert ops in order gives the new text, the equal and delete ops the
+// old one.
+type Op struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+var tokenRegexp = regexp.MustCompile(`\s+|[^\s]+`)
+
+// Words diffs a and b word by word, whitespace runs counting as words so the
+// ops join back into the original texts.
+func Words(a, b string) []Op {
+	return Tokens(tokenRegexp.FindAllString(a, -1), tokenRegexp.FindAllString(b, -1))
+}
+
+// Tokens diffs two token lists using their longest common subsequence.
+// Consecutive tokens of the same kind are merged into one op.
+func Tokens(a, b []string) []Op {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := []Op{}
+	push := func(kind, token string) {
+		if n := len(ops); n > 0 && ops[n-1].Type == kind {
+			ops[n-1].Text += token
+			return
+		}
+		ops = append(ops, Op{Type: kind, Text: token})
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			push(Equal, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			push(Delete, a[i])
+			i++
+		default:
+			push(Insert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		push(Delete, a[i])
+	}
+	for ; j < len(b); j++ {
+		push(Insert, b[j])
+	}
+
+	return ops
+}
+
+// Sets returns the items of b missing from a, and of a missing from b.
+func Sets(a, b []string) (added, removed []string) {
+	added, removed = []string{}, []string{}
+
+	for _, item := range b {
+		if !slices.Contains(a, item) {
+			added = append(added, item)
+		}
+	}
+	for _, item := range a {
+		if !slices.Contains(b, item) {
+			removed = append(removed, item)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,56 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	t.Run("should diff word by word", func(t *testing.T) {
+		ops := Words("the quick brown fox", "the slow brown fox jumps")
+
+		expected := []Op{
+			{Equal, "the "},
+			{Delete, "quick"},
+			{Insert, "slow"},
+			{Equal, " brown fox"},
+			{Insert, " jumps"},
+		}
+		if !reflect.DeepEqual(ops, expected) {
+			t.Errorf("expected %v, got %v", expected, ops)
+		}
+	})
+
+	t.Run("should rebuild both texts from the ops", func(t *testing.T) {
+		a, b := "hello  world\nbye", "hello world\n\nsee you"
+
+		var old, new strings.Builder
+		for _, op := range Words(a, b) {
+			if op.Type != Insert {
+				old.WriteString(op.Text)
+			}
+			if op.Type != Delete {
+				new.WriteString(op.Text)
+			}
+		}
+
+		if old.String() != a || new.String() != b {
+			t.Errorf("got %q and %q", old.String(), new.String())
+		}
+	})
+
+	t.Run("should return no ops for two empty texts", func(t *testing.T) {
+		if ops := Words("", ""); len(ops) != 0 {
+			t.Errorf("expected no ops, got %v", ops)
+		}
+	})
+}
+
+func TestSets(t *testing.T) {
+	added, removed := Sets([]string{"go", "sql"}, []string{"go", "redis"})
+
+	if !reflect.DeepEqual(added, []string{"redis"}) || !reflect.DeepEqual(removed, []string{"sql"}) {
+		t.Errorf("got added %v, removed %v", added, removed)
+	}
+}
@@ -0,0 +1,64 @@
+package imaging
+
+// MaxFrames bounds the number of frames of a GIF.
+const MaxFrames = 1000
+
+// gifFrames walks the blocks of a GIF without decoding them, and returns the
+// number of frames and the pixels they decode to in total. It stops counting
+// once either goes past its bound, and leaves malformed data to the decoder.
+func gifFrames(data []byte) (frames, pixels int) {
+	// header and logical screen descriptor
+	i := 13
+	if len(data) < i {
+		return 0, 0
+	}
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+
+	for i < len(data) && frames <= MaxFrames && pixels <= MaxPixels {
+		switch data[i] {
+		case 0x21: // extension: label then sub-blocks
+			i = skipSubBlocks(data, i+2)
+
+		case 0x2C: // image descriptor
+			if i+10 > len(data) {
+				return frames, pixels
+			}
+
+			width := int(data[i+5]) | int(data[i+6])<<8
+			height := int(data[i+7]) | int(data[i+8])<<8
+			frames++
+			pixels += width * height
+
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+
+			// LZW minimum code size then the image data sub-blocks
+			i = skipSubBlocks(data, i+1)
+
+		default: // trailer or garbage
+			return frames, pixels
+		}
+	}
+
+	return frames, pixels
+}
+
+// skipSubBlocks returns the index past the sub-blocks starting at i, each
+// prefixed with its size and ended by an empty one.
+func skipSubBlocks(data []byte, i int) int {
+	for i < len(data) {
+		size := int(data[i])
+		i++
+		if size == 0 {
+			break
+		}
+		i += size
+	}
+
+	return i
+}
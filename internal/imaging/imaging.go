@@ -0,0 +1,210 @@
+// Package imaging validates uploaded images and prepares them for serving:
+// the image is decoded and encoded again, which drops EXIF and any other
+// metadata, and a thumbnail is generated.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Content types that can be processed.
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypeGIF  = "image/gif"
+)
+
+// MaxPixels bounds the decoded size of an image, as a small file can expand
+// to a huge bitmap.
+const MaxPixels = 40_000_000
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooLarge        = errors.New("image dimensions too large")
+)
+
+type Image struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+}
+
+// Process sniffs the content type of data, which has to be a JPEG, PNG or GIF
+// image, and returns it without metadata along with a thumbnail fitting in a
+// thumbSize square. JPEGs are rotated upright according to their EXIF
+// orientation, since the tag does not survive.
+func Process(data []byte, thumbSize int) (original, thumbnail *Image, err error) {
+	contentType := http.DetectContentType(data)
+
+	switch contentType {
+	case TypeJPEG, TypePNG, TypeGIF:
+	default:
+		return nil, nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrUnsupportedType
+	}
+
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, nil, ErrTooLarge
+	}
+
+	// every frame of a GIF is decoded at once
+	if contentType == TypeGIF {
+		frames, pixels := gifFrames(data)
+		if frames > MaxFrames || pixels > MaxPixels {
+			return nil, nil, ErrTooLarge
+		}
+	}
+
+	var (
+		img image.Image
+		buf bytes.Buffer
+	)
+
+	switch contentType {
+	case TypeJPEG:
+		decoded, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, ErrUnsupportedType
+		}
+
+		img = orient(toRGBA(decoded), exifOrientation(data))
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+		if err != nil {
+			return nil, nil, err
+		}
+
+	case TypePNG:
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, ErrUnsupportedType
+		}
+
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, nil, err
+		}
+
+	case TypeGIF:
+		// keep every frame, the extensions that can carry metadata are dropped
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, ErrUnsupportedType
+		}
+		anim.Config = image.Config{}
+
+		img = anim.Image[0]
+		if err := gif.EncodeAll(&buf, anim); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	bounds := img.Bounds()
+	original = &Image{
+		ContentType: contentType,
+		Data:        buf.Bytes(),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}
+
+	thumbnail, err = makeThumbnail(img, thumbSize, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return original, thumbnail, nil
+}
+
+// makeThumbnail scales img down to fit in a size square. Photos become JPEGs,
+// the rest PNGs to keep transparency.
+func makeThumbnail(img image.Image, size int, contentType string) (*Image, error) {
+	thumb := resize(toRGBA(img), size)
+
+	var buf bytes.Buffer
+	thumbType := TypePNG
+	if contentType == TypeJPEG {
+		thumbType = TypeJPEG
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := png.Encode(&buf, thumb); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Image{
+		ContentType: thumbType,
+		Data:        buf.Bytes(),
+		Width:       thumb.Bounds().Dx(),
+		Height:      thumb.Bounds().Dy(),
+	}, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+
+	return rgba
+}
+
+// resize scales src down so that it fits in a size square, averaging the
+// source pixels covered by each destination pixel. Smaller images are
+// returned as is.
+func resize(src *image.RGBA, size int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw <= size && sh <= size {
+		return src
+	}
+
+	dw, dh := size, size
+	if sw > sh {
+		dh = max(1, sh*size/sw)
+	} else {
+		dw = max(1, sw*size/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
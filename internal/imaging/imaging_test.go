@@ -0,0 +1,150 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withOrientation inserts an EXIF segment with the orientation tag right after
+// the start of image marker.
+func withOrientation(data []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big endian header, IFD0 at 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, // orientation, SHORT, count 1
+		0, 0, 0, 0, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, 0xE1, 0, byte(len(payload) + 2)}, payload...)
+
+	return append(append([]byte{0xFF, 0xD8}, segment...), data[2:]...)
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("should strip EXIF and turn the image upright", func(t *testing.T) {
+		data := withOrientation(encodeJPEG(t, 400, 200), 6)
+		if exifOrientation(data) != 6 {
+			t.Fatal("expected the test image to carry orientation 6")
+		}
+
+		original, thumbnail, err := Process(data, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bytes.Contains(original.Data, []byte("Exif")) {
+			t.Error("expected EXIF to be stripped")
+		}
+		if original.Width != 200 || original.Height != 400 {
+			t.Errorf("expected 200x400, got %dx%d", original.Width, original.Height)
+		}
+		if thumbnail.ContentType != TypeJPEG || thumbnail.Width != 50 || thumbnail.Height != 100 {
+			t.Errorf("expected a 50x100 jpeg thumbnail, got %s %dx%d", thumbnail.ContentType, thumbnail.Width, thumbnail.Height)
+		}
+	})
+
+	t.Run("should keep small images as their own thumbnail", func(t *testing.T) {
+		var buf bytes.Buffer
+		png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 10, 20)))
+
+		_, thumbnail, err := Process(buf.Bytes(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if thumbnail.ContentType != TypePNG || thumbnail.Width != 10 || thumbnail.Height != 20 {
+			t.Errorf("expected a 10x20 png thumbnail, got %s %dx%d", thumbnail.ContentType, thumbnail.Width, thumbnail.Height)
+		}
+	})
+
+	t.Run("should reject GIFs whose frames add up to too many pixels", func(t *testing.T) {
+		// 4000x4000 frames of a single-entry sub-block each, far too small
+		// to be decoded but counted before any decoding happens
+		data := []byte("GIF89a\xa0\x0f\xa0\x0f\x80\x00\x00\x00\x00\x00\xff\xff\xff")
+		for i := 0; i < 3; i++ {
+			data = append(data, 0x2C, 0, 0, 0, 0, 0xa0, 0x0f, 0xa0, 0x0f, 0, 2, 1, 0, 0)
+		}
+		data = append(data, 0x3B)
+
+		if _, _, err := Process(data, 100); err != ErrTooLarge {
+			t.Errorf("expected ErrTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("should reject GIFs with too many frames", func(t *testing.T) {
+		var buf bytes.Buffer
+		anim := &gif.GIF{}
+		palette := color.Palette{color.Black, color.White}
+		for i := 0; i <= MaxFrames; i++ {
+			anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), palette))
+			anim.Delay = append(anim.Delay, 0)
+		}
+		if err := gif.EncodeAll(&buf, anim); err != nil {
+			t.Fatal(err)
+		}
+
+		if frames, _ := gifFrames(buf.Bytes()); frames != MaxFrames+1 {
+			t.Fatalf("expected %d frames counted, got %d", MaxFrames+1, frames)
+		}
+
+		if _, _, err := Process(buf.Bytes(), 100); err != ErrTooLarge {
+			t.Errorf("expected ErrTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("should keep the frames of small GIFs", func(t *testing.T) {
+		var buf bytes.Buffer
+		anim := &gif.GIF{}
+		palette := color.Palette{color.Black, color.White}
+		for i := 0; i < 3; i++ {
+			anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 10, 10), palette))
+			anim.Delay = append(anim.Delay, 10)
+		}
+		if err := gif.EncodeAll(&buf, anim); err != nil {
+			t.Fatal(err)
+		}
+
+		if frames, pixels := gifFrames(buf.Bytes()); frames != 3 || pixels != 300 {
+			t.Errorf("expected 3 frames of 300 pixels, got %d frames of %d", frames, pixels)
+		}
+
+		original, _, err := Process(buf.Bytes(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := gif.DecodeAll(bytes.NewReader(original.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded.Image) != 3 {
+			t.Errorf("expected 3 frames, got %d", len(decoded.Image))
+		}
+	})
+
+	t.Run("should reject other content", func(t *testing.T) {
+		if _, _, err := Process([]byte("<svg></svg>"), 100); err != ErrUnsupportedType {
+			t.Errorf("expected ErrUnsupportedType, got %v", err)
+		}
+	})
+}
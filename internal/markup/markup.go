@@ -0,0 +1,163 @@
+// Package markup finds the @mentions and #hashtags in post content.
+package markup
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kinds of entities.
+const (
+	Mention = "mention"
+	Hashtag = "hashtag"
+)
+
+const (
+	maxMentionLength = 255
+	maxHashtagLength = 100
+)
+
+// Entity is a mention or hashtag found in a text. Start and End are offsets in
+// characters (Unicode code points), End being exclusive, and cover the sigil.
+// Value is the username or the normalized, lower cased, hashtag.
+type Entity struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Parse returns the entities of text in order of appearance. A sigil only
+// starts an entity at the beginning of a word, so e-mail addresses and URL
+// fragments are left alone.
+func Parse(text string) []Entity {
+	entities := []Entity{}
+
+	var (
+		prev  rune
+		chars int // characters before i
+	)
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		if (r == '@' || r == '#') && !isWordChar(prev) && prev != '@' && prev != '#' {
+			var (
+				body  string
+				valid func(rune) bool
+			)
+			if r == '@' {
+				valid = isUsernameChar
+			} else {
+				valid = isHashtagChar
+			}
+
+			end := i + size
+			for end < len(text) {
+				next, n := utf8.DecodeRuneInString(text[end:])
+				if !valid(next) {
+					break
+				}
+				end += n
+			}
+			body = text[i+size : end]
+
+			if r == '@' {
+				// a trailing dot ends the sentence rather than the username
+				body = strings.TrimRight(body, ".")
+			}
+
+			if entity, ok := newEntity(r, body); ok {
+				length := utf8.RuneCountInString(body) + 1
+				entity.Start = chars
+				entity.End = chars + length
+
+				entities = append(entities, entity)
+
+				i += size + len(body)
+				chars += length
+				prev = lastRune(body)
+				continue
+			}
+		}
+
+		prev = r
+		i += size
+		chars++
+	}
+
+	return entities
+}
+
+// Hashtags returns the distinct hashtags of text.
+func Hashtags(text string) []string {
+	return values(Parse(text), Hashtag)
+}
+
+// Mentions returns the distinct usernames mentioned in text.
+func Mentions(text string) []string {
+	return values(Parse(text), Mention)
+}
+
+// NormalizeHashtag lower cases a hashtag and strips its sigil, returning false
+// when it is not a valid hashtag.
+func NormalizeHashtag(tag string) (string, bool) {
+	tag = strings.TrimPrefix(tag, "#")
+	for _, r := range tag {
+		if !isHashtagChar(r) {
+			return "", false
+		}
+	}
+
+	entity, ok := newEntity('#', tag)
+	return entity.Value, ok
+}
+
+func newEntity(sigil rune, body string) (Entity, bool) {
+	if sigil == '@' {
+		if body == "" || len(body) > maxMentionLength {
+			return Entity{}, false
+		}
+		return Entity{Type: Mention, Value: body}, true
+	}
+
+	// hashtags need a letter, #1 is not a tag
+	if utf8.RuneCountInString(body) > maxHashtagLength || strings.IndexFunc(body, unicode.IsLetter) < 0 {
+		return Entity{}, false
+	}
+
+	return Entity{Type: Hashtag, Value: strings.ToLower(body)}, true
+}
+
+func values(entities []Entity, kind string) []string {
+	seen := map[string]bool{}
+	values := []string{}
+
+	for _, e := range entities {
+		key := strings.ToLower(e.Value)
+		if e.Type == kind && !seen[key] {
+			seen[key] = true
+			values = append(values, e.Value)
+		}
+	}
+
+	return values
+}
+
+func isWordChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isUsernameChar(r rune) bool {
+	return r < utf8.RuneSelf && (isWordChar(r) || r == '.')
+}
+
+func isHashtagChar(r rune) bool {
+	return isWordChar(r) || unicode.Is(unicode.Mn, r)
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
@@ -0,0 +1,46 @@
+package markup
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []Entity
+	}{
+		{
+			"hi @alice, see #GoLang",
+			[]Entity{
+				{Mention, "alice", 3, 9},
+				{Hashtag, "golang", 15, 22},
+			},
+		},
+		{
+			// offsets count characters, not bytes
+			"café #crème @bob.",
+			[]Entity{
+				{Hashtag, "crème", 5, 11},
+				{Mention, "bob", 12, 16},
+			},
+		},
+		{"mail me at bob@example.com", []Entity{}},
+		{"issue #1 and ##double", []Entity{}},
+		{"#a#b", []Entity{{Hashtag, "a", 0, 2}}},
+		{"@first.last ok", []Entity{{Mention, "first.last", 0, 11}}},
+	}
+
+	for _, tt := range tests {
+		if got := Parse(tt.text); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Parse(%q) = %v, expected %v", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	got := Hashtags("#Go and #go and #SQL")
+	if !reflect.DeepEqual(got, []string{"go", "sql"}) {
+		t.Errorf("expected distinct normalized hashtags, got %v", got)
+	}
+}
@@ -0,0 +1,127 @@
+// Package ranking orders feed candidates by relevance. Scores only depend on
+// the candidates and on the time of the ranking, so a ranking can be
+// reproduced and explained.
+package ranking
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Candidate is a post considered for a feed with the signals scorers use.
+type Candidate struct {
+	PostID    int64
+	AuthorID  int64
+	CreatedAt time.Time
+	Comments  int
+	Reactions int
+	// FollowsAuthor and Interactions, the viewer's recent comments and
+	// reactions on posts of the author, measure the viewer's affinity with
+	// the author
+	FollowsAuthor bool
+	Interactions  int
+}
+
+// Scorer scores a candidate ranked at now. Higher scores rank first.
+type Scorer interface {
+	Score(c Candidate, now time.Time) float64
+}
+
+// ScorerFunc adapts a function to Scorer.
+type ScorerFunc func(c Candidate, now time.Time) float64
+
+func (f ScorerFunc) Score(c Candidate, now time.Time) float64 {
+	return f(c, now)
+}
+
+// Recency decays exponentially with the age of a post, from 1 when it is
+// created, halving every HalfLife.
+type Recency struct {
+	HalfLife time.Duration
+}
+
+func (s Recency) Score(c Candidate, now time.Time) float64 {
+	age := now.Sub(c.CreatedAt)
+	if age < 0 {
+		age = 0
+	}
+
+	return math.Exp2(-age.Seconds() / s.HalfLife.Seconds())
+}
+
+// Comments grows logarithmically with the number of comments on a post.
+var Comments = ScorerFunc(func(c Candidate, _ time.Time) float64 {
+	return math.Log1p(float64(c.Comments))
+})
+
+// Reactions grows logarithmically with the number of reactions to a post.
+var Reactions = ScorerFunc(func(c Candidate, _ time.Time) float64 {
+	return math.Log1p(float64(c.Reactions))
+})
+
+// Affinity is 1 for authors the viewer follows, plus a logarithm of the
+// viewer's interactions with them.
+var Affinity = ScorerFunc(func(c Candidate, _ time.Time) float64 {
+	score := math.Log1p(float64(c.Interactions))
+	if c.FollowsAuthor {
+		score++
+	}
+	return score
+})
+
+// Weighted is a named scorer and the weight of its scores in a rank.
+type Weighted struct {
+	Name   string
+	Weight float64
+	Scorer Scorer
+}
+
+// Ranker ranks candidates by the weighted sum of the scores of its scorers.
+type Ranker []Weighted
+
+// NewRanker returns the default ranker, favoring recent posts.
+func NewRanker(halfLife time.Duration) Ranker {
+	return Ranker{
+		{Name: "recency", Weight: 3, Scorer: Recency{HalfLife: halfLife}},
+		{Name: "comments", Weight: 1, Scorer: Comments},
+		{Name: "reactions", Weight: 0.5, Scorer: Reactions},
+		{Name: "affinity", Weight: 1, Scorer: Affinity},
+	}
+}
+
+// Ranked is a ranked candidate. Components are the weighted scores making up
+// its score, by scorer name.
+type Ranked struct {
+	Candidate
+	Score      float64
+	Components map[string]float64
+}
+
+// Rank scores the candidates at now and returns them best first. Ties go to
+// the newest post, then to the highest id, so rankings are stable.
+func (r Ranker) Rank(candidates []Candidate, now time.Time) []Ranked {
+	ranked := make([]Ranked, len(candidates))
+	for i, c := range candidates {
+		ranked[i] = Ranked{Candidate: c, Components: make(map[string]float64, len(r))}
+
+		for _, w := range r {
+			score := w.Weight * w.Scorer.Score(c, now)
+			ranked[i].Components[w.Name] = score
+			ranked[i].Score += score
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.PostID > b.PostID
+	})
+
+	return ranked
+}
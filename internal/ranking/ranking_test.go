@@ -0,0 +1,89 @@
+package ranking
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRecency(t *testing.T) {
+	recency := Recency{HalfLife: 6 * time.Hour}
+
+	tests := []struct {
+		age  time.Duration
+		want float64
+	}{
+		{0, 1},
+		{-time.Hour, 1},
+		{6 * time.Hour, 0.5},
+		{12 * time.Hour, 0.25},
+	}
+
+	for _, tt := range tests {
+		got := recency.Score(Candidate{CreatedAt: now.Add(-tt.age)}, now)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("age %v: got %v, expected %v", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestAffinity(t *testing.T) {
+	if got := Affinity.Score(Candidate{}, now); got != 0 {
+		t.Errorf("no affinity: got %v", got)
+	}
+	if got := Affinity.Score(Candidate{FollowsAuthor: true}, now); got != 1 {
+		t.Errorf("following: got %v, expected 1", got)
+	}
+	if got := Affinity.Score(Candidate{FollowsAuthor: true, Interactions: 3}, now); math.Abs(got-(1+math.Log(4))) > 1e-9 {
+		t.Errorf("following with interactions: got %v, expected %v", got, 1+math.Log(4))
+	}
+}
+
+func TestRank(t *testing.T) {
+	ranker := NewRanker(6 * time.Hour)
+
+	candidates := []Candidate{
+		// old but much discussed
+		{PostID: 1, CreatedAt: now.Add(-24 * time.Hour), Comments: 200, Reactions: 500},
+		// fresh
+		{PostID: 2, CreatedAt: now.Add(-10 * time.Minute)},
+		// as fresh, from an author the viewer interacts with
+		{PostID: 3, CreatedAt: now.Add(-10 * time.Minute), FollowsAuthor: true, Interactions: 5},
+		// ties with 2, older
+		{PostID: 4, CreatedAt: now.Add(-20 * time.Minute)},
+		// ties with 4 on everything but the id
+		{PostID: 5, CreatedAt: now.Add(-20 * time.Minute)},
+	}
+
+	ranked := ranker.Rank(candidates, now)
+
+	var got []int64
+	for _, r := range ranked {
+		got = append(got, r.PostID)
+	}
+	if want := []int64{1, 3, 2, 5, 4}; !slices.Equal(got, want) {
+		t.Fatalf("got order %v, expected %v", got, want)
+	}
+
+	for _, r := range ranked {
+		var sum float64
+		for _, w := range ranker {
+			sum += r.Components[w.Name]
+		}
+		if sum != r.Score {
+			t.Errorf("post %d: components add up to %v, score is %v", r.PostID, sum, r.Score)
+		}
+	}
+
+	// the same candidates ranked at the same time rank the same
+	slices.Reverse(candidates)
+	again := ranker.Rank(candidates, now)
+	for i := range ranked {
+		if again[i].PostID != ranked[i].PostID || again[i].Score != ranked[i].Score {
+			t.Fatalf("ranking is not deterministic: %v then %v", ranked, again)
+		}
+	}
+}
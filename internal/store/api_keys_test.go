@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAPIKeys(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	store := &APIKeyStore{db}
+
+	key := &APIKey{UserID: alice, Name: "bot", Prefix: "sk_secret", Scopes: []string{ScopePostsRead}}
+	if err := store.Create(ctx, key, "sk_secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("keys are stored hashed", func(t *testing.T) {
+		var stored string
+		if err := db.QueryRow(`SELECT key FROM api_keys WHERE id = $1`, key.ID).Scan(&stored); err != nil {
+			t.Fatal(err)
+		}
+		if stored == "sk_secret" {
+			t.Error("expected the key not to be stored in plain text")
+		}
+	})
+
+	t.Run("looking up a key", func(t *testing.T) {
+		got, err := store.GetByKey(ctx, "sk_secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != key.ID || got.UserID != alice || !got.HasScope(ScopePostsRead) {
+			t.Errorf("got key %+v, expected %+v", got, key)
+		}
+
+		if _, err := store.GetByKey(ctx, "sk_other"); !errors.Is(err, ErrorNotFound) {
+			t.Errorf("got %v looking up an unknown key, expected %v", err, ErrorNotFound)
+		}
+	})
+
+	t.Run("last use", func(t *testing.T) {
+		if err := store.Touch(ctx, key.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.GetByKey(ctx, "sk_secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.LastUsedAt == nil {
+			t.Error("expected the key to have been used")
+		}
+	})
+
+	t.Run("revoking a key", func(t *testing.T) {
+		if err := store.Revoke(ctx, bob, key.ID); !errors.Is(err, ErrorNotFound) {
+			t.Errorf("got %v revoking a key of another user, expected %v", err, ErrorNotFound)
+		}
+
+		if err := store.Revoke(ctx, alice, key.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := store.GetByKey(ctx, "sk_secret"); !errors.Is(err, ErrorNotFound) {
+			t.Errorf("got %v looking up a revoked key, expected %v", err, ErrorNotFound)
+		}
+
+		keys, err := store.GetByUserID(ctx, alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 0 {
+			t.Errorf("got %d keys, expected revoked keys to be left out", len(keys))
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// MediaPath is where the API serves blobs, attachment URLs are relative to
+// the API address.
+const MediaPath = "/v1/media/"
+
+var ErrorAttachmentLimit = errors.New("too many attachments")
+
+// Attachment is an image attached to a post. The blobs live in the blob store
+// under Key and ThumbnailKey.
+type Attachment struct {
+	ID           int64  `json:"id"`
+	PostID       int64  `json:"post_id"`
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	CreatedAt    string `json:"created_at"`
+}
+
+func (a *Attachment) setURLs() {
+	a.URL = MediaPath + a.Key
+	a.ThumbnailURL = MediaPath + a.ThumbnailKey
+}
+
+type AttachmentStore struct {
+	db *sql.DB
+}
+
+// Create adds an attachment to a post that has fewer than max of them.
+func (store *AttachmentStore) Create(ctx context.Context, attachment *Attachment, max int) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// lock the post so concurrent uploads can not go over the limit
+		var count int
+		err := tx.QueryRowContext(ctx, `
+			SELECT (SELECT COUNT(*) FROM attachments WHERE post_id = p.id)
+			FROM posts p WHERE p.id = $1
+			FOR UPDATE
+		`, attachment.PostID).Scan(&count)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+
+		if count >= max {
+			return ErrorAttachmentLimit
+		}
+
+		query := `
+			INSERT INTO attachments (post_id, key, thumbnail_key, content_type, size, width, height)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at
+		`
+
+		err = tx.QueryRowContext(ctx, query,
+			attachment.PostID,
+			attachment.Key,
+			attachment.ThumbnailKey,
+			attachment.ContentType,
+			attachment.Size,
+			attachment.Width,
+			attachment.Height,
+		).Scan(&attachment.ID, &attachment.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		attachment.setURLs()
+
+		return nil
+	})
+}
+
+// Delete removes an attachment of a post and returns it, so its blobs can be
+// deleted as well.
+func (store *AttachmentStore) Delete(ctx context.Context, postID, id int64) (*Attachment, error) {
+	query := `
+		DELETE FROM attachments WHERE id = $1 AND post_id = $2
+		RETURNING id, post_id, key, thumbnail_key, content_type, size, width, height, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var a Attachment
+	err := store.db.QueryRowContext(ctx, query, id, postID).Scan(
+		&a.ID,
+		&a.PostID,
+		&a.Key,
+		&a.ThumbnailKey,
+		&a.ContentType,
+		&a.Size,
+		&a.Width,
+		&a.Height,
+		&a.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &a, nil
+}
+
+// GetPost returns the post the blob under key is attached to, as the original
+// or the thumbnail of an attachment, with what is needed to tell who may see
+// it.
+func (store *AttachmentStore) GetPost(ctx context.Context, key string) (*Post, error) {
+	query := `
+		SELECT p.id, p.user_id, p.status, p.visibility
+		FROM attachments a
+		JOIN posts p ON p.id = a.post_id
+		WHERE a.key = $1 OR a.thumbnail_key = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var post Post
+	err := store.db.QueryRowContext(ctx, query, key).Scan(
+		&post.ID,
+		&post.UserID,
+		&post.Status,
+		&post.Visibility,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &post, nil
+}
+
+// Keys returns the blob keys of the attachments of a post.
+func (store *AttachmentStore) Keys(ctx context.Context, postID int64) ([]string, error) {
+	attachments, err := postAttachments(ctx, store.db, []int64{postID})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, a := range attachments[postID] {
+		keys = append(keys, a.Key, a.ThumbnailKey)
+	}
+
+	return keys, nil
+}
+
+// postAttachments loads the attachments of the posts in upload order. Every
+// requested post has an entry.
+func postAttachments(ctx context.Context, db *sql.DB, postIDs []int64) (map[int64][]Attachment, error) {
+	attachments := make(map[int64][]Attachment, len(postIDs))
+	for _, id := range postIDs {
+		attachments[id] = []Attachment{}
+	}
+
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+
+	query := `
+		SELECT id, post_id, key, thumbnail_key, content_type, size, width, height, created_at
+		FROM attachments
+		WHERE post_id = ANY($1)
+		ORDER BY id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Attachment
+		err := rows.Scan(
+			&a.ID,
+			&a.PostID,
+			&a.Key,
+			&a.ThumbnailKey,
+			&a.ContentType,
+			&a.Size,
+			&a.Width,
+			&a.Height,
+			&a.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		a.setURLs()
+		attachments[a.PostID] = append(attachments[a.PostID], a)
+	}
+
+	return attachments, rows.Err()
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAttachmentGetPost(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	post := createTestPost(t, db, &Post{
+		UserID: alice, Title: "private", Content: "private", Tags: []string{},
+		Visibility: PostVisibilityPrivate,
+	}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	store := &AttachmentStore{db}
+	attachment := &Attachment{
+		PostID: post.ID, Key: "posts/image.png", ThumbnailKey: "posts/image_thumb.png",
+		ContentType: "image/png", Size: 3, Width: 1, Height: 1,
+	}
+	if err := store.Create(ctx, attachment, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{attachment.Key, attachment.ThumbnailKey} {
+		got, err := store.GetPost(ctx, key)
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		if got.ID != post.ID || got.UserID != alice || got.Visibility != PostVisibilityPrivate {
+			t.Errorf("%s: expected post %d of user %d, got %+v", key, post.ID, alice, got)
+		}
+	}
+
+	if _, err := store.GetPost(ctx, "posts/other.png"); !errors.Is(err, ErrorNotFound) {
+		t.Errorf("expected ErrorNotFound, got %v", err)
+	}
+}
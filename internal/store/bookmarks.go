@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type BookmarkCollection struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+	CreatedAt string `json:"created_at"`
+}
+
+// BookmarkedPost is a post as listed in a collection, in the same shape as the
+// feed.
+type BookmarkedPost struct {
+	PostWithMetadata
+	BookmarkID   int64  `json:"-"`
+	BookmarkedAt string `json:"bookmarked_at"`
+}
+
+type BookmarkStore struct {
+	db *sql.DB
+}
+
+func (store *BookmarkStore) CreateCollection(ctx context.Context, collection *BookmarkCollection) error {
+	query := `
+		INSERT INTO bookmark_collections (user_id, name)
+		VALUES ($1, $2) RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := store.db.QueryRowContext(ctx, query, collection.UserID, collection.Name).Scan(
+		&collection.ID,
+		&collection.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "bookmark_collections_user_id_name_key"`:
+			return ErrorConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *BookmarkStore) GetCollections(ctx context.Context, userID int64) ([]BookmarkCollection, error) {
+	query := `
+		SELECT bc.id, bc.user_id, bc.name, bc.created_at, COUNT(b.id)
+		FROM bookmark_collections bc
+		LEFT JOIN bookmarks b ON b.collection_id = bc.id
+		WHERE bc.user_id = $1
+		GROUP BY bc.id
+		ORDER BY bc.name
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []BookmarkCollection{}
+	for rows.Next() {
+		var c BookmarkCollection
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.CreatedAt, &c.PostCount); err != nil {
+			return nil, err
+		}
+
+		collections = append(collections, c)
+	}
+
+	return collections, rows.Err()
+}
+
+func (store *BookmarkStore) DeleteCollection(ctx context.Context, userID, id int64) error {
+	query := `DELETE FROM bookmark_collections WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := store.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// Add saves a post to one of the user's collections. Saving it twice is a
+// no-op.
+func (store *BookmarkStore) Add(ctx context.Context, userID, collectionID, postID int64) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		if err := store.checkOwner(ctx, tx, userID, collectionID); err != nil {
+			return err
+		}
+
+		// posts the user may not see are treated as missing
+		query := `
+			INSERT INTO bookmarks (collection_id, post_id)
+			SELECT $1, p.id FROM posts p WHERE p.id = $2 AND ` + visiblePostSQL("p", "$3", false) + `
+			ON CONFLICT (collection_id, post_id) DO NOTHING
+			RETURNING 1
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var inserted int
+		err := tx.QueryRowContext(ctx, query, collectionID, postID, userID).Scan(&inserted)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return store.checkBookmarked(ctx, tx, collectionID, postID)
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Remove takes a post out of one of the user's collections.
+func (store *BookmarkStore) Remove(ctx context.Context, userID, collectionID, postID int64) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		if err := store.checkOwner(ctx, tx, userID, collectionID); err != nil {
+			return err
+		}
+
+		query := `DELETE FROM bookmarks WHERE collection_id = $1 AND post_id = $2`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		_, err := tx.ExecContext(ctx, query, collectionID, postID)
+		return err
+	})
+}
+
+// ListPosts returns a page of a collection, ordered by when the posts were
+// saved. Posts the user can no longer see are left out.
+func (store *BookmarkStore) ListPosts(ctx context.Context, userID, collectionID int64, cq CursorQuery) ([]BookmarkedPost, error) {
+	var posts []BookmarkedPost
+
+	err := WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		if err := store.checkOwner(ctx, tx, userID, collectionID); err != nil {
+			return err
+		}
+
+		keyset, page, args := cq.keyset("b.created_at", "b.id", 3)
+
+		query := `
+			SELECT ` + postListColumns + `, b.id, b.created_at
+			FROM bookmarks b
+			JOIN posts p ON p.id = b.post_id
+			LEFT JOIN users u ON u.id = p.user_id
+			WHERE b.collection_id = $1 AND ` + visiblePostSQL("p", "$2", false) + ` AND ` + keyset + page
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		rows, err := tx.QueryContext(ctx, query, append([]any{collectionID, userID}, args...)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		posts = []BookmarkedPost{}
+		for rows.Next() {
+			var post BookmarkedPost
+			if err := scanPostWithMetadata(rows, &post.PostWithMetadata, &post.BookmarkID, &post.BookmarkedAt); err != nil {
+				return err
+			}
+
+			posts = append(posts, post)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*Post, len(posts))
+	for i := range posts {
+		list[i] = &posts[i].Post
+	}
+
+	if err := attachDetails(ctx, store.db, list, userID); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// checkBookmarked tells a post already in the collection, which is fine, from
+// a post that cannot be bookmarked.
+func (store *BookmarkStore) checkBookmarked(ctx context.Context, tx *sql.Tx, collectionID, postID int64) error {
+	query := `SELECT EXISTS (SELECT 1 FROM bookmarks WHERE collection_id = $1 AND post_id = $2)`
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, query, collectionID, postID).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// checkOwner returns ErrorNotFound unless the collection belongs to the user,
+// so other users' collections are indistinguishable from missing ones.
+func (store *BookmarkStore) checkOwner(ctx context.Context, tx *sql.Tx, userID, collectionID int64) error {
+	query := `SELECT 1 FROM bookmark_collections WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists int
+	err := tx.QueryRowContext(ctx, query, collectionID, userID).Scan(&exists)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrorNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
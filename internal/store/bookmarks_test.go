@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBookmarks(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := func(title string) int64 {
+		return createTestPost(t, db, &Post{UserID: bob, Title: title, Content: title, Tags: []string{}}, day).ID
+	}
+	first := post("first")
+	second := post("second")
+
+	store := &BookmarkStore{db}
+
+	collection := func(userID int64, name string) int64 {
+		t.Helper()
+		c := &BookmarkCollection{UserID: userID, Name: name}
+		if err := store.CreateCollection(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		return c.ID
+	}
+	saved := func(userID, collectionID int64) []int64 {
+		t.Helper()
+		posts, err := store.ListPosts(ctx, userID, collectionID, CursorQuery{Limit: 20, Sort: "asc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int64
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	aliceSaved := collection(alice, "saved")
+	aliceLater := collection(alice, "later")
+	bobSaved := collection(bob, "saved")
+
+	t.Run("collection names are unique per user", func(t *testing.T) {
+		err := store.CreateCollection(ctx, &BookmarkCollection{UserID: alice, Name: "saved"})
+		if err != ErrorConflict {
+			t.Errorf("got %v, expected %v", err, ErrorConflict)
+		}
+	})
+
+	t.Run("saving a post twice is a no-op", func(t *testing.T) {
+		for _, id := range []int64{first, first, second} {
+			if err := store.Add(ctx, alice, aliceSaved, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got, want := saved(alice, aliceSaved), []int64{first, second}; !slices.Equal(got, want) {
+			t.Errorf("got bookmarked posts %v, expected %v", got, want)
+		}
+	})
+
+	t.Run("collections of other users are missing", func(t *testing.T) {
+		if err := store.Add(ctx, alice, bobSaved, first); err != ErrorNotFound {
+			t.Errorf("got %v saving, expected %v", err, ErrorNotFound)
+		}
+		if err := store.Remove(ctx, alice, bobSaved, first); err != ErrorNotFound {
+			t.Errorf("got %v removing, expected %v", err, ErrorNotFound)
+		}
+		if _, err := store.ListPosts(ctx, alice, bobSaved, CursorQuery{Limit: 20, Sort: "asc"}); err != ErrorNotFound {
+			t.Errorf("got %v listing, expected %v", err, ErrorNotFound)
+		}
+		if err := store.DeleteCollection(ctx, alice, bobSaved); err != ErrorNotFound {
+			t.Errorf("got %v deleting, expected %v", err, ErrorNotFound)
+		}
+	})
+
+	t.Run("deleted posts leave their collections", func(t *testing.T) {
+		if err := store.Add(ctx, alice, aliceLater, first); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := (&PostStore{db}).Delete(ctx, first); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := saved(alice, aliceSaved), []int64{second}; !slices.Equal(got, want) {
+			t.Errorf("got bookmarked posts %v, expected %v", got, want)
+		}
+
+		collections, err := store.GetCollections(ctx, alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range collections {
+			if c.ID == aliceLater && c.PostCount != 0 {
+				t.Errorf("got %d posts in the collection, expected none", c.PostCount)
+			}
+		}
+	})
+
+	t.Run("deleting a collection deletes its bookmarks", func(t *testing.T) {
+		if err := store.DeleteCollection(ctx, alice, aliceSaved); err != nil {
+			t.Fatal(err)
+		}
+
+		var left int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM bookmarks WHERE collection_id = $1`, aliceSaved).Scan(&left); err != nil {
+			t.Fatal(err)
+		}
+		if left != 0 {
+			t.Errorf("got %d bookmarks left in the deleted collection", left)
+		}
+	})
+
+	t.Run("own drafts keep their status", func(t *testing.T) {
+		draft := createTestPost(t, db, &Post{UserID: alice, Title: "draft", Content: "draft", Tags: []string{}, Status: PostStatusDraft}, day).ID
+
+		drafts := collection(alice, "drafts")
+		for _, id := range []int64{draft, second} {
+			if err := store.Add(ctx, alice, drafts, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		posts, err := store.ListPosts(ctx, alice, drafts, CursorQuery{Limit: 20, Sort: "asc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		statuses := map[int64]string{}
+		for _, post := range posts {
+			statuses[post.ID] = post.Status
+		}
+		if want := map[int64]string{draft: PostStatusDraft, second: PostStatusPublished}; !maps.Equal(statuses, want) {
+			t.Errorf("got statuses %v, expected %v", statuses, want)
+		}
+	})
+}
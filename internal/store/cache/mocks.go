@@ -2,13 +2,15 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/umeh-promise/social/internal/store"
 )
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:     &MockUserStore{},
+		Timelines: &MockTimelineStore{},
 	}
 }
 
@@ -21,3 +23,29 @@ func (m *MockUserStore) Get(context.Context, int64) (*store.User, error) {
 func (m *MockUserStore) Set(context.Context, *store.User) error {
 	return nil
 }
+
+func (m *MockUserStore) Delete(context.Context, int64) error {
+	return nil
+}
+
+type MockTimelineStore struct{}
+
+func (m *MockTimelineStore) Build(context.Context, int64, []store.TimelineEntry) error {
+	return nil
+}
+
+func (m *MockTimelineStore) Push(context.Context, []int64, ...store.TimelineEntry) error {
+	return nil
+}
+
+func (m *MockTimelineStore) Remove(context.Context, []int64, ...store.TimelineEntry) error {
+	return nil
+}
+
+func (m *MockTimelineStore) RemoveAuthor(context.Context, int64, int64) error {
+	return nil
+}
+
+func (m *MockTimelineStore) Range(context.Context, int64, time.Time, time.Time, int) (*TimelineWindow, error) {
+	return nil, nil
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/umeh-promise/social/internal/store"
@@ -11,11 +12,20 @@ type Storage struct {
 	Users interface {
 		Get(context.Context, int64) (*store.User, error)
 		Set(context.Context, *store.User) error
+		Delete(context.Context, int64) error
+	}
+	Timelines interface {
+		Build(context.Context, int64, []store.TimelineEntry) error
+		Push(context.Context, []int64, ...store.TimelineEntry) error
+		Remove(context.Context, []int64, ...store.TimelineEntry) error
+		RemoveAuthor(context.Context, int64, int64) error
+		Range(context.Context, int64, time.Time, time.Time, int) (*TimelineWindow, error)
 	}
 }
 
 func NewCacheStorage(rdb *redis.Client) Storage {
 	return Storage{
-		Users: &UserStore{rdb},
+		Users:     &UserStore{rdb},
+		Timelines: &TimelineStore{rdb},
 	}
 }
@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/umeh-promise/social/internal/store"
+)
+
+// TimelineStore keeps the home timelines of the users as sorted sets of
+// "<post id>:<author id>" members scored by the creation time of the posts.
+// A sentinel member scored 0 marks a timeline as built, so that posts are
+// only pushed to timelines that hold everything before them.
+type TimelineStore struct {
+	rdb *redis.Client
+}
+
+// TimelineLength is the number of posts a timeline is trimmed to.
+const TimelineLength = 800
+
+const (
+	timelineSentinel = "-"
+	// timelineBatch is the number of timelines updated at once
+	timelineBatch = 500
+)
+
+// timelineExpTime is how long the timeline of a user who stopped reading it is
+// kept up to date.
+var timelineExpTime = time.Hour * 24 * 7
+
+// TimelineWindow is a window of a timeline, newest first.
+type TimelineWindow struct {
+	PostIDs []int64
+	// Oldest is the creation time of the oldest post of the window
+	Oldest time.Time
+	// Full is set when the window was cut short, older posts follow it
+	Full bool
+	// Truncated is set when the timeline was trimmed, posts older than the
+	// ones it holds may be missing from it
+	Truncated bool
+}
+
+// pushTimelineScript adds posts, given as score and member pairs after the
+// length to trim to, to the built timelines among KEYS and trims them, keeping
+// the sentinel.
+var pushTimelineScript = redis.NewScript(`
+local length = tonumber(ARGV[1])
+for _, key in ipairs(KEYS) do
+	if redis.call('EXISTS', key) == 1 then
+		redis.call('ZADD', key, unpack(ARGV, 2))
+		redis.call('ZREMRANGEBYRANK', key, 1, -(length + 1))
+	end
+end
+return 0
+`)
+
+func timelineKey(userID int64) string {
+	return fmt.Sprintf("timeline-%d", userID)
+}
+
+func timelineMember(entry store.TimelineEntry) string {
+	return fmt.Sprintf("%d:%d", entry.PostID, entry.UserID)
+}
+
+// Build replaces the timeline of the user with entries, the newest posts of
+// their timeline.
+func (redisStore *TimelineStore) Build(ctx context.Context, userID int64, entries []store.TimelineEntry) error {
+	members := []*redis.Z{{Score: 0, Member: timelineSentinel}}
+	for _, entry := range entries {
+		members = append(members, &redis.Z{Score: float64(entry.CreatedAt.Unix()), Member: timelineMember(entry)})
+	}
+
+	key := timelineKey(userID)
+	_, err := redisStore.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.ZAdd(ctx, key, members...)
+		pipe.ZRemRangeByRank(ctx, key, 1, -(TimelineLength + 1))
+		pipe.Expire(ctx, key, timelineExpTime)
+		return nil
+	})
+	return err
+}
+
+// Push adds posts to the timelines of the users, those that are built.
+func (redisStore *TimelineStore) Push(ctx context.Context, userIDs []int64, entries ...store.TimelineEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	args := []any{TimelineLength}
+	for _, entry := range entries {
+		args = append(args, entry.CreatedAt.Unix(), timelineMember(entry))
+	}
+
+	for start := 0; start < len(userIDs); start += timelineBatch {
+		batch := userIDs[start:min(start+timelineBatch, len(userIDs))]
+
+		keys := make([]string, len(batch))
+		for i, userID := range batch {
+			keys[i] = timelineKey(userID)
+		}
+
+		err := pushTimelineScript.Run(ctx, redisStore.rdb, keys, args...).Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove takes posts out of the timelines of the users.
+func (redisStore *TimelineStore) Remove(ctx context.Context, userIDs []int64, entries ...store.TimelineEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	members := make([]any, len(entries))
+	for i, entry := range entries {
+		members[i] = timelineMember(entry)
+	}
+
+	for start := 0; start < len(userIDs); start += timelineBatch {
+		batch := userIDs[start:min(start+timelineBatch, len(userIDs))]
+
+		_, err := redisStore.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, userID := range batch {
+				pipe.ZRem(ctx, timelineKey(userID), members...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveAuthor takes the posts of an author out of the timeline of the user.
+func (redisStore *TimelineStore) RemoveAuthor(ctx context.Context, userID, authorID int64) error {
+	key := timelineKey(userID)
+
+	members, err := redisStore.rdb.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	suffix := ":" + strconv.FormatInt(authorID, 10)
+
+	var remove []any
+	for _, member := range members {
+		if strings.HasSuffix(member, suffix) {
+			remove = append(remove, member)
+		}
+	}
+	if len(remove) == 0 {
+		return nil
+	}
+
+	return redisStore.rdb.ZRem(ctx, key, remove...).Err()
+}
+
+// Range returns the count newest posts of the timeline of the user created
+// between since and until, both inclusive and ignored when zero. It returns
+// nil when the timeline is not built.
+func (redisStore *TimelineStore) Range(ctx context.Context, userID int64, since, until time.Time, count int) (*TimelineWindow, error) {
+	key := timelineKey(userID)
+
+	maxScore := "+inf"
+	if !until.IsZero() {
+		maxScore = strconv.FormatInt(until.Unix(), 10)
+	}
+	// leaves the sentinel out
+	minScore := "(0"
+	if !since.IsZero() {
+		minScore = strconv.FormatInt(since.Unix(), 10)
+	}
+
+	var (
+		members *redis.ZSliceCmd
+		card    *redis.IntCmd
+	)
+	_, err := redisStore.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		members = pipe.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Max:   maxScore,
+			Min:   minScore,
+			Count: int64(count),
+		})
+		card = pipe.ZCard(ctx, key)
+		pipe.Expire(ctx, key, timelineExpTime)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Val() == 0 {
+		return nil, nil
+	}
+
+	window := &TimelineWindow{
+		PostIDs:   []int64{},
+		Full:      len(members.Val()) == count,
+		Truncated: card.Val()-1 >= TimelineLength,
+	}
+
+	for _, z := range members.Val() {
+		member, _ := z.Member.(string)
+
+		postID, _, _ := strings.Cut(member, ":")
+		id, err := strconv.ParseInt(postID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeline member %q", member)
+		}
+
+		window.PostIDs = append(window.PostIDs, id)
+		window.Oldest = time.Unix(int64(z.Score), 0)
+	}
+
+	return window, nil
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/umeh-promise/social/internal/store"
+)
+
+// TestTimelineStoreRedis runs against the Redis at TEST_REDIS_ADDR, whose
+// database 15 it flushes.
+func TestTimelineStoreRedis(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	rdb := NewCacheClient(addr, "", 15)
+	t.Cleanup(func() { rdb.Close() })
+
+	ctx := context.Background()
+	if err := rdb.FlushDB(ctx).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	timelines := &TimelineStore{rdb}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(postID, userID int64) store.TimelineEntry {
+		return store.TimelineEntry{PostID: postID, UserID: userID, CreatedAt: start.Add(time.Duration(postID) * time.Minute)}
+	}
+
+	window, err := timelines.Range(ctx, 1, time.Time{}, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if window != nil {
+		t.Fatalf("expected no timeline before it is built, got %+v", window)
+	}
+
+	// posts are only pushed to built timelines
+	if err := timelines.Push(ctx, []int64{1, 2}, entry(1, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if n := rdb.Exists(ctx, timelineKey(1), timelineKey(2)).Val(); n != 0 {
+		t.Fatalf("expected no timelines, %d were created", n)
+	}
+
+	if err := timelines.Build(ctx, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := timelines.Push(ctx, []int64{1, 2}, entry(1, 3), entry(2, 4), entry(3, 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	expectRange := func(since, until time.Time, count int, want []int64, full bool) {
+		t.Helper()
+
+		window, err := timelines.Range(ctx, 1, since, until, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if window == nil {
+			t.Fatal("expected a timeline")
+		}
+		if !slices.Equal(window.PostIDs, want) || window.Full != full || window.Truncated {
+			t.Fatalf("got %+v, expected posts %v full=%v", window, want, full)
+		}
+	}
+
+	expectRange(time.Time{}, time.Time{}, 10, []int64{3, 2, 1}, false)
+	expectRange(time.Time{}, time.Time{}, 2, []int64{3, 2}, true)
+	expectRange(entry(2, 0).CreatedAt, time.Time{}, 10, []int64{3, 2}, false)
+	expectRange(time.Time{}, entry(2, 0).CreatedAt, 10, []int64{2, 1}, false)
+
+	if err := timelines.RemoveAuthor(ctx, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	expectRange(time.Time{}, time.Time{}, 10, []int64{2}, false)
+
+	if err := timelines.Remove(ctx, []int64{1}, entry(2, 4)); err != nil {
+		t.Fatal(err)
+	}
+	// an empty timeline is still built
+	expectRange(time.Time{}, time.Time{}, 10, []int64{}, false)
+
+	var entries []store.TimelineEntry
+	for id := int64(1); id <= TimelineLength+5; id++ {
+		entries = append(entries, entry(id, 3))
+	}
+	if err := timelines.Push(ctx, []int64{1}, entries...); err != nil {
+		t.Fatal(err)
+	}
+
+	window, err = timelines.Range(ctx, 1, time.Time{}, time.Time{}, TimelineLength+10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(window.PostIDs) != TimelineLength || !window.Truncated || window.PostIDs[len(window.PostIDs)-1] != 6 {
+		t.Fatalf("expected the timeline trimmed to its newest %d posts, got %d down to %d, truncated=%v",
+			TimelineLength, len(window.PostIDs), window.PostIDs[len(window.PostIDs)-1], window.Truncated)
+	}
+}
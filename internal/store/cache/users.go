@@ -45,3 +45,8 @@ func (redisStore *UserStore) Set(ctx context.Context, user *store.User) error {
 
 	return redisStore.rdb.SetEX(ctx, cacheKey, json, userExpTime).Err()
 }
+
+func (redisStore *UserStore) Delete(ctx context.Context, id int64) error {
+	cacheKey := fmt.Sprintf("user-%d", id)
+	return redisStore.rdb.Del(ctx, cacheKey).Err()
+}
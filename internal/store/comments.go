@@ -3,34 +3,43 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 )
 
 type Comment struct {
-	ID        int64  `json:"id"`
-	UserID    int64  `json:"user_id"`
-	PostID    int64  `json:"post_id"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	User      User   `json:"user"`
+	ID         int64  `json:"id"`
+	UserID     int64  `json:"user_id"`
+	PostID     int64  `json:"post_id"`
+	ParentID   *int64 `json:"parent_id"`
+	Content    string `json:"content"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+	ReplyCount int    `json:"reply_count"`
+	User       User   `json:"user"`
+	ReactionSummary
 }
 
 type CommentStore struct {
 	db *sql.DB
 }
 
-func (store *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
+// List returns a page of the comments on a post replying to parentID, or the
+// top level comments when parentID is nil.
+func (store *CommentStore) List(ctx context.Context, postID int64, parentID *int64, cq CursorQuery) ([]Comment, error) {
+	keyset, page, args := cq.keyset("c.created_at", "c.id", 3)
 
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.id, users.username from comments c
-		JOIN users on users.id= c.user_id
-		WHERE c.post_id = $1
-		ORDER BY c.created_at;
-	`
+		SELECT c.id, c.post_id, c.parent_id, c.user_id, c.content, c.created_at, c.updated_at,
+			(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count,
+			users.id, users.username
+		FROM comments c
+		JOIN users on users.id = c.user_id
+		WHERE c.post_id = $1 AND c.parent_id IS NOT DISTINCT FROM $2::bigint AND ` + keyset + page
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := store.db.QueryContext(ctx, query, postID)
+	rows, err := store.db.QueryContext(ctx, query, append([]any{postID, parentID}, args...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -41,8 +50,18 @@ func (store *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Com
 
 	for rows.Next() {
 		var c Comment
-		c.User = User{}
-		err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Content, &c.CreatedAt, &c.User.ID, &c.User.Username)
+		err := rows.Scan(
+			&c.ID,
+			&c.PostID,
+			&c.ParentID,
+			&c.UserID,
+			&c.Content,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+			&c.ReplyCount,
+			&c.User.ID,
+			&c.User.Username,
+		)
 		if err != nil {
 			return nil, err
 		}
@@ -50,26 +69,132 @@ func (store *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Com
 		comments = append(comments, c)
 	}
 
-	return comments, nil
+	return comments, rows.Err()
+}
+
+func (store *CommentStore) GetByID(ctx context.Context, id int64) (*Comment, error) {
+	query := `
+		SELECT c.id, c.post_id, c.parent_id, c.user_id, c.content, c.created_at, c.updated_at,
+			(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count,
+			users.id, users.username
+		FROM comments c
+		JOIN users on users.id = c.user_id
+		WHERE c.id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	c := &Comment{}
+	err := store.db.QueryRowContext(ctx, query, id).Scan(
+		&c.ID,
+		&c.PostID,
+		&c.ParentID,
+		&c.UserID,
+		&c.Content,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+		&c.ReplyCount,
+		&c.User.ID,
+		&c.User.Username,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
 
+	return c, nil
 }
 
+// Create adds a comment. A reply must be to a comment on the same post,
+// otherwise ErrorNotFound is returned.
 func (store *CommentStore) Create(ctx context.Context, comment *Comment) error {
 
 	query := `
-		INSERT INTO comments(user_id, post_id, content)
-		VALUES($1, $2, $3)
-		RETURNING id, created_at;
+		INSERT INTO comments(user_id, post_id, parent_id, content)
+		SELECT $1, $2, $3::bigint, $4
+		WHERE $3::bigint IS NULL OR EXISTS (SELECT 1 FROM comments WHERE id = $3 AND post_id = $2)
+		RETURNING id, created_at, updated_at;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := store.db.QueryRowContext(ctx, query, comment.UserID, comment.PostID, comment.Content).Scan(&comment.ID, &comment.CreatedAt)
+	err := store.db.QueryRowContext(ctx, query, comment.UserID, comment.PostID, comment.ParentID, comment.Content).Scan(
+		&comment.ID,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
 
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrorNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *CommentStore) Update(ctx context.Context, comment *Comment) error {
+	query := `
+		UPDATE comments SET content = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := store.db.QueryRowContext(ctx, query, comment.Content, comment.ID).Scan(&comment.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrorNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a comment together with its replies.
+func (store *CommentStore) Delete(ctx context.Context, id int64) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		if err := deleteCommentReactions(ctx, tx, id); err != nil {
+			return err
+		}
+
+		return store.delete(ctx, tx, id)
+	})
+}
+
+func (store *CommentStore) delete(ctx context.Context, tx *sql.Tx, id int64) error {
+	query := `DELETE FROM comments WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
 
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
 	return nil
 }
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCommentThreads(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := createTestPost(t, db, &Post{UserID: alice, Title: "post", Content: "post", Tags: []string{}}, day).ID
+	other := createTestPost(t, db, &Post{UserID: alice, Title: "other", Content: "other", Tags: []string{}}, day).ID
+
+	store := &CommentStore{db}
+	comment := func(userID, postID int64, parentID *int64) int64 {
+		c := &Comment{UserID: userID, PostID: postID, ParentID: parentID, Content: "comment"}
+		if err := store.Create(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		return c.ID
+	}
+
+	top := comment(bob, post, nil)
+	second := comment(alice, post, nil)
+	reply := comment(alice, post, &top)
+	nested := comment(bob, post, &reply)
+
+	ids := func(comments []Comment) []int64 {
+		var ids []int64
+		for _, c := range comments {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	cq := CursorQuery{Limit: 20, Sort: "asc"}
+
+	t.Run("top level comments", func(t *testing.T) {
+		comments, err := store.List(ctx, post, nil, cq)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := ids(comments), []int64{top, second}; !slices.Equal(got, want) {
+			t.Fatalf("got comments %v, expected %v", got, want)
+		}
+		if comments[0].ReplyCount != 1 || comments[1].ReplyCount != 0 {
+			t.Errorf("got reply counts %d and %d, expected 1 and 0", comments[0].ReplyCount, comments[1].ReplyCount)
+		}
+	})
+
+	t.Run("replies", func(t *testing.T) {
+		comments, err := store.List(ctx, post, &top, cq)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := ids(comments), []int64{reply}; !slices.Equal(got, want) {
+			t.Errorf("got replies %v, expected %v", got, want)
+		}
+	})
+
+	t.Run("replies to comments on another post", func(t *testing.T) {
+		c := &Comment{UserID: bob, PostID: other, ParentID: &top, Content: "reply"}
+		if err := store.Create(ctx, c); err != ErrorNotFound {
+			t.Errorf("got %v, expected %v", err, ErrorNotFound)
+		}
+	})
+
+	t.Run("deleting a comment deletes its replies", func(t *testing.T) {
+		reactions := &ReactionStore{db}
+		if err := reactions.Set(ctx, alice, ReactionTargetComment, nested, "like"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.Delete(ctx, top); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, id := range []int64{top, reply, nested} {
+			if _, err := store.GetByID(ctx, id); err != ErrorNotFound {
+				t.Errorf("got %v loading comment %d, expected %v", err, id, ErrorNotFound)
+			}
+		}
+		if _, err := store.GetByID(ctx, second); err != nil {
+			t.Errorf("got %v loading the other comment", err)
+		}
+
+		var left int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM reactions WHERE target_type = $1 AND target_id = $2`, ReactionTargetComment, nested).Scan(&left); err != nil {
+			t.Fatal(err)
+		}
+		if left != 0 {
+			t.Errorf("got %d reactions left on a deleted reply", left)
+		}
+	})
+
+	t.Run("deleting the post deletes its comments", func(t *testing.T) {
+		if err := (&PostStore{db}).Delete(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := store.GetByID(ctx, second); err != ErrorNotFound {
+			t.Errorf("got %v, expected %v", err, ErrorNotFound)
+		}
+	})
+}
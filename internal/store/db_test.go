@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// newTestDB migrates a schema of its own in the database at TEST_DB_ADDR, a
+// postgres:// URL, and drops it when the test is done. Tests needing a
+// database are skipped when it is not set.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		t.Skip("TEST_DB_ADDR is not set")
+	}
+
+	admin, err := sql.Open("postgres", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("dropping %s: %v", schema, err)
+		}
+	})
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema+",public")
+	u.RawQuery = q.Encode()
+
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := filepath.Glob("../../cmd/migrate/migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(migrations)
+
+	for _, migration := range migrations {
+		query, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Exec(string(query)); err != nil {
+			t.Fatalf("%s: %v", filepath.Base(migration), err)
+		}
+	}
+
+	return db
+}
+
+// createTestUser inserts an active user with the user role.
+func createTestUser(t *testing.T, db *sql.DB, username string) int64 {
+	t.Helper()
+
+	var id int64
+	err := db.QueryRow(`
+		INSERT INTO users (username, email, password, is_active, role_id)
+		VALUES ($1, $1 || '@example.com', '', true, (SELECT id FROM roles WHERE name = 'user'))
+		RETURNING id
+	`, username).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// createTestPost creates a post through the store, then backdates it.
+func createTestPost(t *testing.T, db *sql.DB, post *Post, createdAt time.Time) *Post {
+	t.Helper()
+
+	store := &PostStore{db}
+	if err := store.Create(context.Background(), post); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(`UPDATE posts SET created_at = $1 WHERE id = $2`, createdAt, post.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	return post
+}
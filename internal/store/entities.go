@@ -0,0 +1,213 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/umeh-promise/social/internal/markup"
+)
+
+// PostEntity is a mention or hashtag in the content of a post, for clients to
+// linkify. Mentions carry the id of the user mentioned, mentions of unknown
+// users are left out.
+type PostEntity struct {
+	markup.Entity
+	UserID int64 `json:"user_id,omitempty"`
+}
+
+// syncEntities indexes the hashtags of the post, from its content and its
+// tags, and the users it mentions.
+func syncEntities(ctx context.Context, tx *sql.Tx, post *Post) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	hashtags := markup.Hashtags(post.Content)
+	for _, tag := range post.Tags {
+		if tag, ok := markup.NormalizeHashtag(tag); ok && !slices.Contains(hashtags, tag) {
+			hashtags = append(hashtags, tag)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_hashtags WHERE post_id = $1`, post.ID); err != nil {
+		return err
+	}
+
+	if len(hashtags) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO hashtags (name) SELECT UNNEST($1::varchar[])
+			ON CONFLICT (name) DO NOTHING
+		`, pq.Array(hashtags))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO post_hashtags (post_id, hashtag_id)
+			SELECT $1, id FROM hashtags WHERE name = ANY($2)
+		`, post.ID, pq.Array(hashtags))
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_mentions WHERE post_id = $1`, post.ID); err != nil {
+		return err
+	}
+
+	mentions := markup.Mentions(post.Content)
+	if len(mentions) > 0 {
+		for i := range mentions {
+			mentions[i] = strings.ToLower(mentions[i])
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO post_mentions (post_id, user_id)
+			SELECT $1, id FROM users WHERE lower(username) = ANY($2)
+		`, post.ID, pq.Array(mentions))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// postEntities parses the content of the posts, resolving mentions to the
+// users indexed by syncEntities.
+func postEntities(ctx context.Context, db *sql.DB, posts []*Post) (map[int64][]PostEntity, error) {
+	entities := make(map[int64][]PostEntity, len(posts))
+	if len(posts) == 0 {
+		return entities, nil
+	}
+
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	query := `
+		SELECT m.post_id, u.id, lower(u.username)
+		FROM post_mentions m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.post_id = ANY($1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentioned := map[int64]map[string]int64{}
+	for rows.Next() {
+		var (
+			postID, userID int64
+			username       string
+		)
+		if err := rows.Scan(&postID, &userID, &username); err != nil {
+			return nil, err
+		}
+
+		if mentioned[postID] == nil {
+			mentioned[postID] = map[string]int64{}
+		}
+		mentioned[postID][username] = userID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, post := range posts {
+		list := []PostEntity{}
+		for _, e := range markup.Parse(post.Content) {
+			entity := PostEntity{Entity: e}
+
+			if e.Type == markup.Mention {
+				userID, ok := mentioned[post.ID][strings.ToLower(e.Value)]
+				if !ok {
+					continue
+				}
+				entity.UserID = userID
+			}
+
+			list = append(list, entity)
+		}
+		entities[post.ID] = list
+	}
+
+	return entities, nil
+}
+
+// ListByHashtag returns a page of the published posts with a hashtag that
+// viewerID may see.
+func (store *PostStore) ListByHashtag(ctx context.Context, tag string, viewerID int64, cq CursorQuery) ([]PostWithMetadata, error) {
+	keyset, page, args := cq.keyset("p.created_at", "p.id", 3)
+
+	query := `
+		SELECT ` + postListColumns + `
+		FROM post_hashtags ph
+		JOIN hashtags h ON h.id = ph.hashtag_id
+		JOIN posts p ON p.id = ph.post_id
+		LEFT JOIN users u ON u.id = p.user_id
+		WHERE h.name = $1 AND p.status = 'published' AND ` + visiblePostSQL("p", "$2", true) + ` AND ` + keyset + page
+
+	return store.listPosts(ctx, viewerID, query, append([]any{tag, viewerID}, args...)...)
+}
+
+// ListMentioning returns a page of the published posts mentioning the user
+// that they may see. Their own drafts are not mentions yet.
+func (store *PostStore) ListMentioning(ctx context.Context, userID int64, cq CursorQuery) ([]PostWithMetadata, error) {
+	keyset, page, args := cq.keyset("p.created_at", "p.id", 2)
+
+	query := `
+		SELECT ` + postListColumns + `
+		FROM post_mentions m
+		JOIN posts p ON p.id = m.post_id
+		LEFT JOIN users u ON u.id = p.user_id
+		WHERE m.user_id = $1 AND p.status = 'published' AND ` + visiblePostSQL("p", "$1", false) + ` AND ` + keyset + page
+
+	return store.listPosts(ctx, userID, query, append([]any{userID}, args...)...)
+}
+
+// listPosts runs a query selecting postListColumns and attaches the details
+// of the posts for viewerID.
+func (store *PostStore) listPosts(ctx context.Context, viewerID int64, query string, args ...any) ([]PostWithMetadata, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(queryCtx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostWithMetadata{}
+	for rows.Next() {
+		var post PostWithMetadata
+		if err := scanPostWithMetadata(rows, &post); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	list := make([]*Post, len(posts))
+	for i := range posts {
+		list[i] = &posts[i].Post
+	}
+
+	if err := attachDetails(ctx, store.db, list, viewerID); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestListMentioningAndByHashtag(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := func(userID int64, content, status string, at time.Time) int64 {
+		p := &Post{UserID: userID, Title: content, Content: content, Tags: []string{}, Status: status}
+		if status == PostStatusScheduled {
+			publishAt := day.Add(24 * time.Hour).Format(time.RFC3339)
+			p.PublishAt = &publishAt
+		}
+		return createTestPost(t, db, p, at).ID
+	}
+
+	published := post(bob, "hello @alice #go", PostStatusPublished, day)
+	post(alice, "note to @alice #go", PostStatusDraft, day.Add(time.Hour))
+	post(alice, "later @alice #go", PostStatusScheduled, day.Add(2*time.Hour))
+
+	store := &PostStore{db}
+	cq := CursorQuery{Limit: 20, Sort: "desc"}
+
+	mentions, err := store.ListMentioning(ctx, alice, cq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mentions) != 1 || mentions[0].ID != published {
+		t.Errorf("got %d mentions, expected only the published post", len(mentions))
+	}
+
+	tagged, err := store.ListByHashtag(ctx, "go", alice, cq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 1 || tagged[0].ID != published {
+		t.Errorf("got %d tagged posts, expected only the published post", len(tagged))
+	}
+}
@@ -51,3 +51,93 @@ func (store *FollowerStore) Unfollow(ctx context.Context, followerID, userID int
 	_, err := store.db.ExecContext(ctx, query, userID, followerID)
 	return err
 }
+
+// IsFollowing reports whether followerID follows userID.
+func (store *FollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var following bool
+	err := store.db.QueryRowContext(ctx, query, userID, followerID).Scan(&following)
+	return following, err
+}
+
+// FollowEntry is a user in a list of followers or of followed users.
+type FollowEntry struct {
+	ID         int64  `json:"id"`
+	Username   string `json:"username"`
+	FollowedAt string `json:"followed_at"`
+}
+
+// ListFollowers returns a page of the users following userID, ordered by when
+// they followed.
+func (store *FollowerStore) ListFollowers(ctx context.Context, userID int64, cq CursorQuery) ([]FollowEntry, error) {
+	return store.list(ctx, "f.user_id", "f.follower_id", userID, cq)
+}
+
+// ListFollowing returns a page of the users userID follows, ordered by when
+// they were followed.
+func (store *FollowerStore) ListFollowing(ctx context.Context, userID int64, cq CursorQuery) ([]FollowEntry, error) {
+	return store.list(ctx, "f.follower_id", "f.user_id", userID, cq)
+}
+
+// list pages through the users in the other column of the follows whose
+// column is userID.
+func (store *FollowerStore) list(ctx context.Context, column, other string, userID int64, cq CursorQuery) ([]FollowEntry, error) {
+	keyset, page, args := cq.keyset("f.created_at", "u.id", 2)
+
+	query := `
+		SELECT u.id, u.username, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = ` + other + `
+		WHERE ` + column + ` = $1 AND ` + keyset + page
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, append([]any{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []FollowEntry{}
+	for rows.Next() {
+		var entry FollowEntry
+		if err := rows.Scan(&entry.ID, &entry.Username, &entry.FollowedAt); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// ListFollowerIDs returns the ids of up to limit users following userID.
+func (store *FollowerStore) ListFollowerIDs(ctx context.Context, userID int64, limit int) ([]int64, error) {
+	query := `SELECT follower_id FROM followers WHERE user_id = $1 LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Identity links a user to an account at an external identity provider.
+type Identity struct {
+	Provider  string `json:"provider"`
+	Subject   string `json:"-"`
+	UserID    int64  `json:"user_id"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+// OAuthState is what a login started with an identity provider needs to be
+// completed on the callback.
+type OAuthState struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+}
+
+type IdentityStore struct {
+	db *sql.DB
+}
+
+func (store *IdentityStore) CreateState(ctx context.Context, state *OAuthState, exp time.Duration) error {
+	query := `
+		INSERT INTO oauth_states (state, provider, nonce, code_verifier, expiry)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, query,
+		hashToken(state.State),
+		state.Provider,
+		state.Nonce,
+		state.CodeVerifier,
+		time.Now().Add(exp),
+	)
+	return err
+}
+
+// ConsumeState returns and deletes a pending login, so a callback can only be
+// completed once.
+func (store *IdentityStore) ConsumeState(ctx context.Context, state, provider string) (*OAuthState, error) {
+	query := `
+		DELETE FROM oauth_states
+		WHERE state = $1 AND provider = $2 AND expiry > $3
+		RETURNING provider, nonce, code_verifier
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	s := &OAuthState{State: state}
+	err := store.db.QueryRowContext(ctx, query, hashToken(state), provider, time.Now()).Scan(
+		&s.Provider,
+		&s.Nonce,
+		&s.CodeVerifier,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// GetUserID returns the user an external identity is linked to.
+func (store *IdentityStore) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	query := `SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var userID int64
+	err := store.db.QueryRowContext(ctx, query, provider, subject).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrorNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+// Link attaches an identity to an existing user. The provider has verified the
+// email address, so a pending account is activated as if the invitation had
+// been accepted. Whoever registered it never proved they own the address
+// though, so its password, sessions and password resets are dropped and the
+// owner has to reset the password to sign in with one.
+func (store *IdentityStore) Link(ctx context.Context, user *User, identity *Identity) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		identity.UserID = user.ID
+		if err := store.create(ctx, tx, identity); err != nil {
+			return err
+		}
+
+		if user.IsActive {
+			return nil
+		}
+
+		users := &UserStore{store.db}
+
+		// an empty hash matches no password
+		user.Password = password{hash: []byte{}}
+		if err := users.updatePassword(ctx, tx, user); err != nil {
+			return err
+		}
+
+		if err := users.revokeSessions(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		if err := users.deletePasswordResets(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		user.IsActive = true
+		if err := users.update(ctx, tx, user); err != nil {
+			return err
+		}
+
+		return users.deleteUserInvitation(ctx, tx, user.ID)
+	})
+}
+
+// CreateUser creates an active user for an identity, skipping the invitation.
+func (store *IdentityStore) CreateUser(ctx context.Context, user *User, identity *Identity) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		users := &UserStore{store.db}
+
+		if err := users.Create(ctx, tx, user); err != nil {
+			return err
+		}
+
+		user.IsActive = true
+		if err := users.update(ctx, tx, user); err != nil {
+			return err
+		}
+
+		identity.UserID = user.ID
+		return store.create(ctx, tx, identity)
+	})
+}
+
+func (store *IdentityStore) create(ctx context.Context, tx *sql.Tx, identity *Identity) error {
+	query := `
+		INSERT INTO user_identities (provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4) RETURNING created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query,
+		identity.Provider,
+		identity.Subject,
+		identity.UserID,
+		identity.Email,
+	).Scan(&identity.CreatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "user_identities_pkey"`:
+			return ErrorConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
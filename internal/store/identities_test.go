@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdentityLinkPendingAccount(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// someone registered the address and never activated the account
+	var pw password
+	if err := pw.Set("attacker-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	var userID int64
+	err := db.QueryRow(`
+		INSERT INTO users (username, email, password, is_active, role_id)
+		VALUES ('victim', 'victim@example.com', $1, false, (SELECT id FROM roles WHERE name = 'user'))
+		RETURNING id
+	`, pw.hash).Scan(&userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := &SessionStore{db}
+	session := &Session{ID: "pending-session", UserID: userID, UserAgent: "test", IP: "127.0.0.1"}
+	if err := sessions.Create(ctx, session, "refresh-token", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	users := &UserStore{db}
+	user, err := users.GetByID(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identities := &IdentityStore{db}
+	identity := &Identity{Provider: "test", Subject: "victim", Email: "victim@example.com"}
+	if err := identities.Link(ctx, user, identity); err != nil {
+		t.Fatal(err)
+	}
+
+	if !user.IsActive {
+		t.Error("expected the account to be activated")
+	}
+
+	var hash []byte
+	if err := db.QueryRow(`SELECT password FROM users WHERE id = $1`, userID).Scan(&hash); err != nil {
+		t.Fatal(err)
+	}
+	stored := password{hash: hash}
+	if err := stored.Compare("attacker-password"); !errors.Is(err, ErrorInvalidCredentials) {
+		t.Errorf("got %v for the password set before the link, expected %v", err, ErrorInvalidCredentials)
+	}
+
+	active, err := sessions.GetByUserID(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 0 {
+		t.Errorf("got %d sessions, expected them all revoked", len(active))
+	}
+
+	if _, err := sessions.Refresh(ctx, "refresh-token", "new-refresh-token", time.Hour); !errors.Is(err, ErrorNotFound) {
+		t.Errorf("got %v refreshing the session, expected %v", err, ErrorNotFound)
+	}
+}
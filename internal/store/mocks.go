@@ -3,27 +3,46 @@ package store
 import (
 	"context"
 	"database/sql"
+	"slices"
 	"time"
 )
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Posts:     &MockPostStore{},
+		Users:     &MockUserStore{},
+		Comments:  &MockCommentStore{},
+		Followers: &MockFollowerStore{},
+		Sessions: &MockSessionStore{Sessions: map[string]*Session{
+			"test-session": {ID: "test-session", UserID: 1},
+		}},
+		APIKeys:     &MockAPIKeyStore{},
+		Reactions:   &MockReactionStore{},
+		Bookmarks:   &MockBookmarkStore{},
+		Attachments: &MockAttachmentStore{},
 	}
 }
 
-type MockUserStore struct{}
+// MockUserStore serves the users it holds by id, and users without a role
+// otherwise.
+type MockUserStore struct {
+	Users map[int64]*User
+}
 
 func (m *MockUserStore) Create(ctx context.Context, tx *sql.Tx, u *User) error {
 	return nil
 }
 
 func (m *MockUserStore) GetByID(ctx context.Context, id int64) (*User, error) {
-	return &User{}, nil
+	if user, ok := m.Users[id]; ok {
+		return user, nil
+	}
+
+	return &User{ID: id}, nil
 }
 
 func (m *MockUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
-	return &User{}, nil
+	return nil, ErrorNotFound
 }
 
 func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error {
@@ -37,3 +56,352 @@ func (m *MockUserStore) Activate(ctx context.Context, t string) error {
 func (m *MockUserStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
+
+func (m *MockUserStore) CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration) error {
+	return nil
+}
+
+func (m *MockUserStore) ResetPassword(ctx context.Context, token, password string) error {
+	return nil
+}
+
+func (m *MockUserStore) ReplaceInvitation(ctx context.Context, userID int64, token string, exp time.Duration) error {
+	return nil
+}
+
+func (m *MockUserStore) PurgeInactive(ctx context.Context, grace time.Duration) (int64, int64, error) {
+	return 0, 0, nil
+}
+
+func (m *MockUserStore) SetRole(ctx context.Context, userID int64, role string) error {
+	return nil
+}
+
+// MockSessionStore holds sessions by id, starting with the session of the
+// tokens of auth.TestAuthenticator.
+type MockSessionStore struct {
+	Sessions map[string]*Session
+}
+
+func (m *MockSessionStore) Create(ctx context.Context, s *Session, token string, exp time.Duration) error {
+	return nil
+}
+
+func (m *MockSessionStore) GetByID(ctx context.Context, id string) (*Session, error) {
+	session, ok := m.Sessions[id]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return session, nil
+}
+
+func (m *MockSessionStore) GetByUserID(ctx context.Context, userID int64) ([]Session, error) {
+	sessions := []Session{}
+	for _, session := range m.Sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			sessions = append(sessions, *session)
+		}
+	}
+
+	return sessions, nil
+}
+
+func (m *MockSessionStore) Touch(ctx context.Context, id, ip string) error {
+	return nil
+}
+
+func (m *MockSessionStore) Delete(ctx context.Context, userID int64, id string) error {
+	session, ok := m.Sessions[id]
+	if !ok || session.UserID != userID {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (m *MockSessionStore) Refresh(ctx context.Context, token, newToken string, exp time.Duration) (*Session, error) {
+	return nil, ErrorNotFound
+}
+
+func (m *MockSessionStore) Revoke(ctx context.Context, id string) error {
+	return nil
+}
+
+// MockPostStore serves the posts it holds by id.
+type MockPostStore struct {
+	Posts map[int64]*Post
+}
+
+func (m *MockPostStore) Create(ctx context.Context, post *Post) error {
+	return nil
+}
+
+func (m *MockPostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
+	post, ok := m.Posts[id]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return post, nil
+}
+
+func (m *MockPostStore) Update(ctx context.Context, post *Post, version int64) error {
+	return nil
+}
+
+func (m *MockPostStore) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockPostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) Repost(ctx context.Context, post, original *Post) error {
+	return nil
+}
+
+func (m *MockPostStore) Quote(ctx context.Context, post, original *Post) error {
+	return nil
+}
+
+func (m *MockPostStore) DeleteRepost(ctx context.Context, userID int64, original *Post) error {
+	return nil
+}
+
+func (m *MockPostStore) AttachDetails(ctx context.Context, posts []*Post, viewerID int64) error {
+	return nil
+}
+
+func (m *MockPostStore) ListUnpublished(ctx context.Context, userID int64, cq CursorQuery) ([]Post, error) {
+	return []Post{}, nil
+}
+
+func (m *MockPostStore) PublishDue(ctx context.Context, limit int) ([]int64, error) {
+	return nil, nil
+}
+
+func (m *MockPostStore) ListByHashtag(ctx context.Context, tag string, viewerID int64, cq CursorQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) ListMentioning(ctx context.Context, userID int64, cq CursorQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetTimelineFeed(ctx context.Context, userID int64, ids []int64, limit int, since *time.Time, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) TimelineEntries(ctx context.Context, userID int64, limit int) ([]TimelineEntry, error) {
+	return []TimelineEntry{}, nil
+}
+
+func (m *MockPostStore) AuthorEntries(ctx context.Context, userID int64, limit int) ([]TimelineEntry, error) {
+	return []TimelineEntry{}, nil
+}
+
+func (m *MockPostStore) GetFeedCandidates(ctx context.Context, userID int64, fq PaginatedFeedQuery, limit int, at time.Time) ([]FeedCandidate, error) {
+	return []FeedCandidate{}, nil
+}
+
+func (m *MockPostStore) Affinities(ctx context.Context, userID int64, authors []int64, since, until time.Time) (map[int64]Affinity, error) {
+	return map[int64]Affinity{}, nil
+}
+
+// MockCommentStore serves the comments it holds by id.
+type MockCommentStore struct {
+	Comments map[int64]*Comment
+}
+
+func (m *MockCommentStore) List(ctx context.Context, postID int64, parentID *int64, cq CursorQuery) ([]Comment, error) {
+	return []Comment{}, nil
+}
+
+func (m *MockCommentStore) GetByID(ctx context.Context, id int64) (*Comment, error) {
+	comment, ok := m.Comments[id]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return comment, nil
+}
+
+func (m *MockCommentStore) Create(ctx context.Context, comment *Comment) error {
+	return nil
+}
+
+func (m *MockCommentStore) Update(ctx context.Context, comment *Comment) error {
+	return nil
+}
+
+func (m *MockCommentStore) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+// MockFollowerStore holds the ids of the users each user follows.
+type MockFollowerStore struct {
+	Following map[int64][]int64
+}
+
+func (m *MockFollowerStore) Follow(ctx context.Context, followerID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) Unfollow(ctx context.Context, followerID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	return slices.Contains(m.Following[followerID], userID), nil
+}
+
+func (m *MockFollowerStore) ListFollowers(ctx context.Context, userID int64, cq CursorQuery) ([]FollowEntry, error) {
+	return []FollowEntry{}, nil
+}
+
+func (m *MockFollowerStore) ListFollowing(ctx context.Context, userID int64, cq CursorQuery) ([]FollowEntry, error) {
+	return []FollowEntry{}, nil
+}
+
+func (m *MockFollowerStore) ListFollowerIDs(ctx context.Context, userID int64, limit int) ([]int64, error) {
+	return []int64{}, nil
+}
+
+type MockReactionStore struct{}
+
+func (m *MockReactionStore) Set(ctx context.Context, userID int64, targetType string, targetID int64, kind string) error {
+	return nil
+}
+
+func (m *MockReactionStore) Remove(ctx context.Context, userID int64, targetType string, targetID int64, kind string) error {
+	return nil
+}
+
+func (m *MockReactionStore) Summaries(ctx context.Context, targetType string, targetIDs []int64, viewerID int64) (map[int64]*ReactionSummary, error) {
+	summaries := make(map[int64]*ReactionSummary, len(targetIDs))
+	for _, id := range targetIDs {
+		summaries[id] = &ReactionSummary{Reactions: map[string]int{}}
+	}
+
+	return summaries, nil
+}
+
+// MockBookmarkStore holds collections by id. Collections of other users are
+// missing, like in BookmarkStore.
+type MockBookmarkStore struct {
+	Collections map[int64]*BookmarkCollection
+}
+
+func (m *MockBookmarkStore) owns(userID, collectionID int64) error {
+	collection, ok := m.Collections[collectionID]
+	if !ok || collection.UserID != userID {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (m *MockBookmarkStore) CreateCollection(ctx context.Context, collection *BookmarkCollection) error {
+	for _, c := range m.Collections {
+		if c.UserID == collection.UserID && c.Name == collection.Name {
+			return ErrorConflict
+		}
+	}
+
+	return nil
+}
+
+func (m *MockBookmarkStore) GetCollections(ctx context.Context, userID int64) ([]BookmarkCollection, error) {
+	return []BookmarkCollection{}, nil
+}
+
+func (m *MockBookmarkStore) DeleteCollection(ctx context.Context, userID, id int64) error {
+	return m.owns(userID, id)
+}
+
+func (m *MockBookmarkStore) Add(ctx context.Context, userID, collectionID, postID int64) error {
+	return m.owns(userID, collectionID)
+}
+
+func (m *MockBookmarkStore) Remove(ctx context.Context, userID, collectionID, postID int64) error {
+	return m.owns(userID, collectionID)
+}
+
+func (m *MockBookmarkStore) ListPosts(ctx context.Context, userID, collectionID int64, cq CursorQuery) ([]BookmarkedPost, error) {
+	if err := m.owns(userID, collectionID); err != nil {
+		return nil, err
+	}
+
+	return []BookmarkedPost{}, nil
+}
+
+// MockAPIKeyStore holds active keys by their plain text value.
+type MockAPIKeyStore struct {
+	Keys map[string]*APIKey
+}
+
+func (m *MockAPIKeyStore) Create(ctx context.Context, key *APIKey, plainKey string) error {
+	return nil
+}
+
+func (m *MockAPIKeyStore) GetByUserID(ctx context.Context, userID int64) ([]APIKey, error) {
+	keys := []APIKey{}
+	for _, key := range m.Keys {
+		if key.UserID == userID {
+			keys = append(keys, *key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (m *MockAPIKeyStore) GetByKey(ctx context.Context, plainKey string) (*APIKey, error) {
+	key, ok := m.Keys[plainKey]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return key, nil
+}
+
+func (m *MockAPIKeyStore) Touch(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockAPIKeyStore) Revoke(ctx context.Context, userID, id int64) error {
+	for _, key := range m.Keys {
+		if key.ID == id && key.UserID == userID {
+			return nil
+		}
+	}
+
+	return ErrorNotFound
+}
+
+// MockAttachmentStore holds the posts of attachments by blob key.
+type MockAttachmentStore struct {
+	Posts map[string]*Post
+}
+
+func (m *MockAttachmentStore) Create(ctx context.Context, attachment *Attachment, max int) error {
+	return nil
+}
+
+func (m *MockAttachmentStore) Delete(ctx context.Context, postID, id int64) (*Attachment, error) {
+	return nil, ErrorNotFound
+}
+
+func (m *MockAttachmentStore) Keys(ctx context.Context, postID int64) ([]string, error) {
+	return nil, nil
+}
+
+func (m *MockAttachmentStore) GetPost(ctx context.Context, key string) (*Post, error) {
+	post, ok := m.Posts[key]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return post, nil
+}
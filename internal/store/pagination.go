@@ -1,48 +1,56 @@
 package store
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// Feed modes: the latest posts first, or the posts ranked by relevance.
+const (
+	FeedModeLatest = "latest"
+	FeedModeRanked = "ranked"
+)
+
 type PaginatedFeedQuery struct {
-	Limit  int      `json:"limit" validate:"gte=1,lte=20"`
-	Offset int      `json:"offset" validate:"gte=0"`
-	Sort   string   `json:"sort" validate:"oneof=asc desc"`
+	CursorQuery
+	Mode   string   `json:"mode" validate:"oneof=latest ranked"`
 	Tags   []string `json:"tags" validate:"max=5"`
 	Search string   `json:"search" validate:"max=100"`
 	Since  string   `json:"since"`
 	Until  string   `json:"until"`
 }
 
-func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
+// Parse reads the parameters of the feed. The mode a cursor was issued for
+// takes precedence over the mode parameter.
+func (fq PaginatedFeedQuery) Parse(r *http.Request, codec *CursorCodec) (PaginatedFeedQuery, error) {
 	queryString := r.URL.Query()
 
-	limit := queryString.Get("limit")
-	if limit != "" {
-		l, err := strconv.Atoi(limit)
-		if err != nil {
-			return fq, err
-		}
-
-		fq.Limit = l
+	mode := queryString.Get("mode")
+	if mode != "" {
+		fq.Mode = mode
 	}
 
-	offset := queryString.Get("offset")
-	if offset != "" {
-		o, err := strconv.Atoi(offset)
-		if err != nil {
-			return fq, err
-		}
-
-		fq.Offset = o
+	cq, err := fq.CursorQuery.parse(r, codec, true)
+	if err != nil {
+		return fq, err
 	}
+	fq.CursorQuery = cq
 
-	sort := queryString.Get("sort")
-	if sort != "" {
-		fq.Sort = sort
+	if cq.Cursor != nil {
+		fq.Mode = FeedModeLatest
+		if cq.Cursor.Ranked {
+			fq.Mode = FeedModeRanked
+		}
 	}
 
 	tags := queryString.Get("tags")
@@ -57,22 +65,232 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	since := queryString.Get("since")
 	if since != "" {
-		fq.Since = parseTime((since))
+		t, err := parseTime(since)
+		if err != nil {
+			return fq, errors.New("invalid since")
+		}
+
+		fq.Since = t
 	}
 
 	until := queryString.Get("until")
 	if until != "" {
-		fq.Until = parseTime((until))
+		t, err := parseTime(until)
+		if err != nil {
+			return fq, errors.New("invalid until")
+		}
+
+		fq.Until = t
 	}
 
 	return fq, nil
 }
 
-func parseTime(s string) string {
-	t, err := time.Parse(time.DateTime, s)
+// parseTime accepts RFC 3339 timestamps as well as "2006-01-02 15:04:05",
+// taken as UTC, and returns them in RFC 3339 for the database.
+func parseTime(s string) (string, error) {
+	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
-		return ""
+		t, err = time.Parse(time.DateTime, s)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// CursorQuery pages through a list ordered by creation time then id. Cursor
+// is the position the page starts from, nil for the first page.
+type CursorQuery struct {
+	Limit  int    `json:"limit" validate:"gte=1,lte=50"`
+	Sort   string `json:"sort" validate:"oneof=asc desc"`
+	Cursor *Cursor
+}
+
+// Parse reads the limit, sort and cursor parameters of the request. The order
+// a cursor was issued for takes precedence over the sort parameter.
+func (cq CursorQuery) Parse(r *http.Request, codec *CursorCodec) (CursorQuery, error) {
+	return cq.parse(r, codec, false)
+}
+
+// parse is Parse, rejecting the cursors of ranked feeds unless allowRanked.
+func (cq CursorQuery) parse(r *http.Request, codec *CursorCodec, allowRanked bool) (CursorQuery, error) {
+	queryString := r.URL.Query()
+
+	limit := queryString.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return cq, err
+		}
+
+		cq.Limit = l
+	}
+
+	sort := queryString.Get("sort")
+	if sort != "" {
+		cq.Sort = sort
+	}
+
+	cursor := queryString.Get("cursor")
+	if cursor != "" {
+		c, err := codec.Decode(cursor)
+		if err != nil {
+			return cq, err
+		}
+		if c.Ranked && !allowRanked {
+			return cq, ErrorInvalidCursor
+		}
+
+		cq.Cursor = &c
+		cq.Sort = c.Sort
 	}
 
-	return t.Format((time.DateTime))
+	return cq, nil
+}
+
+// keyset returns the condition selecting the rows past the cursor, for a list
+// ordered by the createdAt and id columns, and the ORDER BY and LIMIT clauses
+// walking away from it. Their arguments, bound from $n on, are returned too.
+// One more row than the limit is fetched, see Paginate.
+func (cq CursorQuery) keyset(createdAt, id string, n int) (where, page string, args []any) {
+	dir, op := "ASC", ">"
+	if cq.descending() {
+		dir, op = "DESC", "<"
+	}
+
+	where = fmt.Sprintf("($%d::timestamptz IS NULL OR (%s, %s) %s ($%d::timestamptz, $%d::bigint))",
+		n, createdAt, id, op, n, n+1)
+	page = fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT $%d", createdAt, dir, id, dir, n+2)
+
+	var (
+		at  sql.NullString
+		pos int64
+	)
+	if cq.Cursor != nil {
+		at = sql.NullString{String: cq.Cursor.CreatedAt, Valid: true}
+		pos = cq.Cursor.ID
+	}
+
+	return where, page, []any{at, pos, cq.Limit + 1}
+}
+
+// descending reports whether the rows past the cursor are fetched newest
+// first, walking back in time.
+func (cq CursorQuery) descending() bool {
+	return (cq.Sort == "desc") != (cq.Cursor != nil && cq.Cursor.Prev)
+}
+
+// Paginate turns the rows fetched with a query built by CursorQuery.keyset
+// into a page in list order, with the cursors to the pages after and before
+// it, nil when there is none. position returns the creation time and id of a
+// row.
+func Paginate[T any](rows []T, cq CursorQuery, position func(T) (string, int64)) (page []T, next, prev *Cursor) {
+	backward := cq.Cursor != nil && cq.Cursor.Prev
+
+	more := len(rows) > cq.Limit
+	if more {
+		rows = rows[:cq.Limit]
+	}
+	if backward {
+		slices.Reverse(rows)
+	}
+
+	at := func(row T, prev bool) *Cursor {
+		createdAt, id := position(row)
+		return &Cursor{CreatedAt: createdAt, ID: id, Sort: cq.Sort, Prev: prev}
+	}
+
+	if len(rows) == 0 {
+		// paging past either end, the way back starts from the cursor
+		if cq.Cursor != nil {
+			back := *cq.Cursor
+			back.Prev = !back.Prev
+			if backward {
+				return rows, &back, nil
+			}
+			return rows, nil, &back
+		}
+		return rows, nil, nil
+	}
+
+	if more || backward {
+		next = at(rows[len(rows)-1], false)
+	}
+	if (more && backward) || (!backward && cq.Cursor != nil) {
+		prev = at(rows[0], true)
+	}
+
+	return rows, next, prev
+}
+
+var ErrorInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a list ordered by creation time then id, in the
+// Sort order. Prev marks a cursor to the page before the position rather than
+// the one after.
+//
+// Ranked marks a position in a ranked feed instead: CreatedAt is the time the
+// feed was ranked at and ID the offset of the page in the ranking.
+type Cursor struct {
+	CreatedAt string `json:"t"`
+	ID        int64  `json:"i"`
+	Sort      string `json:"s"`
+	Prev      bool   `json:"p,omitempty"`
+	Ranked    bool   `json:"r,omitempty"`
+}
+
+// CursorCodec turns cursors into opaque strings and back. They are signed so
+// clients cannot forge positions or orders.
+type CursorCodec struct {
+	key []byte
+}
+
+func NewCursorCodec(key []byte) *CursorCodec {
+	return &CursorCodec{key: key}
+}
+
+// cursorMACSize is the size of the truncated HMAC-SHA256 of a cursor.
+const cursorMACSize = 16
+
+func (codec *CursorCodec) Encode(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(append(payload, codec.sign(payload)...))
+}
+
+func (codec *CursorCodec) Decode(s string) (Cursor, error) {
+	var cursor Cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) <= cursorMACSize {
+		return cursor, ErrorInvalidCursor
+	}
+
+	payload, mac := b[:len(b)-cursorMACSize], b[len(b)-cursorMACSize:]
+	if !hmac.Equal(mac, codec.sign(payload)) {
+		return cursor, ErrorInvalidCursor
+	}
+
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return cursor, ErrorInvalidCursor
+	}
+
+	if cursor.Sort != "asc" && cursor.Sort != "desc" {
+		return cursor, ErrorInvalidCursor
+	}
+	if _, err := time.Parse(time.RFC3339Nano, cursor.CreatedAt); err != nil {
+		return cursor, ErrorInvalidCursor
+	}
+	if cursor.Ranked && cursor.ID < 0 {
+		return cursor, ErrorInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+func (codec *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, codec.key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:cursorMACSize]
 }
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPaginatedFeedQueryParseTimes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   bool
+	}{
+		{"2024-01-02T03:04:05Z", "2024-01-02T03:04:05Z", false},
+		{"2024-01-02T04:04:05+01:00", "2024-01-02T03:04:05Z", false},
+		{"2024-01-02 03:04:05", "2024-01-02T03:04:05Z", false},
+		{"yesterday", "", true},
+	}
+
+	for _, tt := range tests {
+		for _, param := range []string{"since", "until"} {
+			r := httptest.NewRequest("GET", "/v1/users/feed?"+param+"="+url.QueryEscape(tt.value), nil)
+
+			fq, err := PaginatedFeedQuery{}.Parse(r, NewCursorCodec([]byte("test")))
+			if tt.err {
+				if err == nil {
+					t.Errorf("%s=%q: expected an error", param, tt.value)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s=%q: %v", param, tt.value, err)
+			}
+
+			got := fq.Since
+			if param == "until" {
+				got = fq.Until
+			}
+			if got != tt.want {
+				t.Errorf("%s=%q: got %q, expected %q", param, tt.value, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestCursorCodec(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	cursor := Cursor{CreatedAt: "2024-01-02T03:04:05Z", ID: 42, Sort: "asc", Prev: true}
+
+	encoded := codec.Encode(cursor)
+	decoded, err := codec.Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != cursor {
+		t.Errorf("got %+v, expected %+v", decoded, cursor)
+	}
+
+	if _, err := NewCursorCodec([]byte("other")).Decode(encoded); err != ErrorInvalidCursor {
+		t.Errorf("decoding with another key: got %v, expected %v", err, ErrorInvalidCursor)
+	}
+
+	tampered := []byte(encoded)
+	tampered[len(tampered)/2] ^= 1
+	if _, err := codec.Decode(string(tampered)); err != ErrorInvalidCursor {
+		t.Errorf("decoding a tampered cursor: got %v, expected %v", err, ErrorInvalidCursor)
+	}
+
+	for _, invalid := range []string{"", "42", strings.Repeat("A", 40)} {
+		if _, err := codec.Decode(invalid); err != ErrorInvalidCursor {
+			t.Errorf("decoding %q: got %v, expected %v", invalid, err, ErrorInvalidCursor)
+		}
+	}
+}
+
+// TestPaginate walks a list of ids 1 to 7, ordered descending, forward then
+// back, emulating the keyset query.
+func TestPaginate(t *testing.T) {
+	list := []int64{7, 6, 5, 4, 3, 2, 1}
+	position := func(id int64) (string, int64) { return "2024-01-01T00:00:00Z", id }
+
+	fetch := func(cq CursorQuery) []int64 {
+		var rows []int64
+		if cq.Cursor == nil {
+			rows = slices.Clone(list)
+		} else if cq.Cursor.Prev {
+			for i := len(list) - 1; i >= 0; i-- {
+				if list[i] > cq.Cursor.ID {
+					rows = append(rows, list[i])
+				}
+			}
+		} else {
+			for _, id := range list {
+				if id < cq.Cursor.ID {
+					rows = append(rows, id)
+				}
+			}
+		}
+		return rows[:min(len(rows), cq.Limit+1)]
+	}
+
+	cq := CursorQuery{Limit: 3, Sort: "desc"}
+	var pages [][]int64
+	for {
+		page, next, prev := Paginate(fetch(cq), cq, position)
+		pages = append(pages, page)
+
+		if (prev != nil) != (cq.Cursor != nil) {
+			t.Errorf("page %v: got prev cursor %+v", page, prev)
+		}
+		if next == nil {
+			break
+		}
+		cq.Cursor = next
+	}
+
+	want := [][]int64{{7, 6, 5}, {4, 3, 2}, {1}}
+	if !slices.EqualFunc(pages, want, slices.Equal) {
+		t.Fatalf("forward: got pages %v, expected %v", pages, want)
+	}
+
+	// back from the last page
+	cq.Cursor = &Cursor{CreatedAt: "2024-01-01T00:00:00Z", ID: 1, Sort: "desc", Prev: true}
+	pages = nil
+	for {
+		page, next, prev := Paginate(fetch(cq), cq, position)
+		pages = append(pages, page)
+
+		if next == nil {
+			t.Errorf("page %v: expected a next cursor", page)
+		}
+		if prev == nil {
+			break
+		}
+		cq.Cursor = prev
+	}
+
+	want = [][]int64{{4, 3, 2}, {7, 6, 5}}
+	if !slices.EqualFunc(pages, want, slices.Equal) {
+		t.Fatalf("backward: got pages %v, expected %v", pages, want)
+	}
+}
+
+func TestPaginatedFeedQueryParseMode(t *testing.T) {
+	codec := NewCursorCodec([]byte("test"))
+	ranked := codec.Encode(Cursor{CreatedAt: "2024-01-02T03:04:05Z", ID: 20, Sort: "desc", Ranked: true})
+	latest := codec.Encode(Cursor{CreatedAt: "2024-01-02T03:04:05Z", ID: 7, Sort: "desc"})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", FeedModeLatest},
+		{"mode=ranked", FeedModeRanked},
+		{"mode=latest&cursor=" + ranked, FeedModeRanked},
+		{"mode=ranked&cursor=" + latest, FeedModeLatest},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/v1/users/feed?"+tt.query, nil)
+
+		fq, err := PaginatedFeedQuery{Mode: FeedModeLatest}.Parse(r, codec)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.query, err)
+		}
+		if fq.Mode != tt.want {
+			t.Errorf("%q: got mode %q, expected %q", tt.query, fq.Mode, tt.want)
+		}
+	}
+
+	// other lists cannot be paged with the cursors of ranked feeds
+	r := httptest.NewRequest("GET", "/v1/users/me/drafts?cursor="+ranked, nil)
+	if _, err := (CursorQuery{Limit: 20, Sort: "desc"}).Parse(r, codec); err != ErrorInvalidCursor {
+		t.Errorf("got %v, expected %v", err, ErrorInvalidCursor)
+	}
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrorPollClosed  = errors.New("poll is closed")
+	ErrorInvalidVote = errors.New("invalid vote")
+)
+
+// Poll is attached to a post. Counts are only filled in once the viewer has
+// voted or the poll is closed, so they can not sway the vote.
+type Poll struct {
+	Multiple    bool         `json:"multiple"`
+	ClosesAt    string       `json:"closes_at"`
+	Closed      bool         `json:"closed"`
+	VotedByMe   bool         `json:"voted_by_me"`
+	TotalVoters *int         `json:"total_voters,omitempty"`
+	Options     []PollOption `json:"options"`
+}
+
+type PollOption struct {
+	ID     int64  `json:"id"`
+	Text   string `json:"text"`
+	Votes  *int   `json:"votes,omitempty"`
+	Chosen bool   `json:"chosen"`
+}
+
+type PollStore struct {
+	db *sql.DB
+}
+
+// Vote casts the user's ballot, a single option unless the poll is multiple
+// choice. A ballot can not be changed, voting again is ErrorConflict.
+func (store *PollStore) Vote(ctx context.Context, postID, userID int64, optionIDs []int64) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var multiple, closed bool
+		err := tx.QueryRowContext(ctx,
+			`SELECT multiple, closes_at <= NOW() FROM polls WHERE post_id = $1`, postID,
+		).Scan(&multiple, &closed)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+
+		if closed {
+			return ErrorPollClosed
+		}
+
+		slices.Sort(optionIDs)
+		optionIDs = slices.Compact(optionIDs)
+		if len(optionIDs) == 0 || (!multiple && len(optionIDs) > 1) {
+			return ErrorInvalidVote
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO poll_ballots (post_id, user_id) VALUES ($1, $2)`, postID, userID)
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "poll_ballots_pkey"`:
+				return ErrorConflict
+			default:
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO poll_votes (post_id, user_id, option_id)
+			SELECT $1, $2, UNNEST($3::bigint[])
+		`, postID, userID, pq.Array(optionIDs))
+		if err != nil {
+			// the options have to belong to the poll
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+				return ErrorInvalidVote
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
+// Get loads the poll of a post as seen by viewerID.
+func (store *PollStore) Get(ctx context.Context, postID, viewerID int64) (*Poll, error) {
+	polls, err := postPolls(ctx, store.db, []int64{postID}, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, ok := polls[postID]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return poll, nil
+}
+
+func createPoll(ctx context.Context, tx *sql.Tx, postID int64, poll *Poll) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO polls (post_id, multiple, closes_at) VALUES ($1, $2, $3)
+		RETURNING closes_at
+	`, postID, poll.Multiple, poll.ClosesAt).Scan(&poll.ClosesAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range poll.Options {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO poll_options (post_id, position, text) VALUES ($1, $2, $3) RETURNING id`,
+			postID, i, poll.Options[i].Text,
+		).Scan(&poll.Options[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// postPolls loads the polls of the posts that have one, with the tallies
+// hidden from viewers who have not voted on an open poll.
+func postPolls(ctx context.Context, db *sql.DB, postIDs []int64, viewerID int64) (map[int64]*Poll, error) {
+	polls := map[int64]*Poll{}
+	if len(postIDs) == 0 {
+		return polls, nil
+	}
+
+	query := `
+		SELECT p.post_id, p.multiple, p.closes_at, p.closes_at <= NOW(),
+			(SELECT COUNT(*) FROM poll_ballots b WHERE b.post_id = p.post_id),
+			EXISTS (SELECT 1 FROM poll_ballots b WHERE b.post_id = p.post_id AND b.user_id = $2),
+			o.id, o.text,
+			(SELECT COUNT(*) FROM poll_votes v WHERE v.option_id = o.id),
+			EXISTS (SELECT 1 FROM poll_votes v WHERE v.option_id = o.id AND v.user_id = $2)
+		FROM polls p
+		JOIN poll_options o ON o.post_id = p.post_id
+		WHERE p.post_id = ANY($1)
+		ORDER BY p.post_id, o.position
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(postIDs), viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			postID int64
+			p      Poll
+			voters int
+			option PollOption
+			votes  int
+		)
+		err := rows.Scan(
+			&postID,
+			&p.Multiple,
+			&p.ClosesAt,
+			&p.Closed,
+			&voters,
+			&p.VotedByMe,
+			&option.ID,
+			&option.Text,
+			&votes,
+			&option.Chosen,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		poll, ok := polls[postID]
+		if !ok {
+			poll = &p
+			if poll.VotedByMe || poll.Closed {
+				poll.TotalVoters = &voters
+			}
+			polls[postID] = poll
+		}
+
+		if poll.TotalVoters != nil {
+			option.Votes = &votes
+		}
+		poll.Options = append(poll.Options, option)
+	}
+
+	return polls, rows.Err()
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPollVote(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	now := time.Now().UTC()
+	poll := func(multiple bool, closesAt time.Time) *Post {
+		post := &Post{UserID: alice, Title: "poll", Content: "poll", Tags: []string{}, Poll: &Poll{
+			Multiple: multiple,
+			ClosesAt: closesAt.Format(time.RFC3339),
+			Options:  []PollOption{{Text: "yes"}, {Text: "no"}, {Text: "maybe"}},
+		}}
+		return createTestPost(t, db, post, now)
+	}
+
+	single := poll(false, now.Add(time.Hour))
+	multiple := poll(true, now.Add(time.Hour))
+	closed := poll(false, now.Add(time.Hour))
+	if _, err := db.Exec(`UPDATE polls SET closes_at = $1 WHERE post_id = $2`, now.Add(-time.Minute), closed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	option := func(post *Post, i int) int64 { return post.Poll.Options[i].ID }
+
+	store := &PollStore{db}
+
+	tests := []struct {
+		name    string
+		post    *Post
+		userID  int64
+		options []int64
+		err     error
+	}{
+		{"single choice", single, bob, []int64{option(single, 0)}, nil},
+		{"voting twice", single, bob, []int64{option(single, 1)}, ErrorConflict},
+		{"several options on a single choice poll", single, carol, []int64{option(single, 0), option(single, 1)}, ErrorInvalidVote},
+		{"the same option twice", single, carol, []int64{option(single, 1), option(single, 1)}, nil},
+		{"several options on a multiple choice poll", multiple, bob, []int64{option(multiple, 0), option(multiple, 2)}, nil},
+		{"an option of another poll", multiple, carol, []int64{option(single, 0)}, ErrorInvalidVote},
+		{"a closed poll", closed, bob, []int64{option(closed, 0)}, ErrorPollClosed},
+		{"a post without a poll", &Post{ID: -1}, bob, []int64{option(single, 0)}, ErrorNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.Vote(ctx, tt.post.ID, tt.userID, tt.options); !errors.Is(err, tt.err) {
+				t.Errorf("got %v, expected %v", err, tt.err)
+			}
+		})
+	}
+
+	// a rejected ballot leaves no trace, the user can still vote
+	if err := store.Vote(ctx, multiple.ID, carol, []int64{option(multiple, 1)}); err != nil {
+		t.Errorf("got %v voting after a rejected ballot", err)
+	}
+}
+
+func TestPollResultsHidden(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	post := createTestPost(t, db, &Post{UserID: alice, Title: "poll", Content: "poll", Tags: []string{}, Poll: &Poll{
+		ClosesAt: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		Options:  []PollOption{{Text: "yes"}, {Text: "no"}},
+	}}, time.Now())
+
+	store := &PollStore{db}
+	if err := store.Vote(ctx, post.ID, bob, []int64{post.Poll.Options[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// carol has not voted on the open poll
+	poll, err := store.Get(ctx, post.ID, carol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if poll.VotedByMe || poll.TotalVoters != nil {
+		t.Errorf("got %+v, expected the tallies hidden", poll)
+	}
+	for _, option := range poll.Options {
+		if option.Votes != nil {
+			t.Errorf("got %d votes for %q, expected them hidden", *option.Votes, option.Text)
+		}
+	}
+
+	// bob has
+	poll, err = store.Get(ctx, post.ID, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !poll.VotedByMe || poll.TotalVoters == nil || *poll.TotalVoters != 1 {
+		t.Fatalf("got %+v, expected the tallies of bob's ballot", poll)
+	}
+	if o := poll.Options[0]; !o.Chosen || o.Votes == nil || *o.Votes != 1 {
+		t.Errorf("got %+v, expected bob's choice with its vote", o)
+	}
+
+	// everybody sees the tallies once the poll is closed
+	if _, err := db.Exec(`UPDATE polls SET closes_at = NOW() - INTERVAL '1 minute' WHERE post_id = $1`, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	poll, err = store.Get(ctx, post.ID, carol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !poll.Closed || poll.TotalVoters == nil || *poll.TotalVoters != 1 {
+		t.Errorf("got %+v, expected the tallies of the closed poll", poll)
+	}
+}
@@ -8,17 +8,92 @@ import (
 	"github.com/lib/pq"
 )
 
+// Post statuses. Drafts and scheduled posts are only visible to their author
+// until they are published.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+)
+
+// Post visibilities. Unlisted posts can be opened by anyone with a link but
+// are left out of feeds and listings.
+const (
+	PostVisibilityPublic    = "public"
+	PostVisibilityFollowers = "followers"
+	PostVisibilityPrivate   = "private"
+	PostVisibilityUnlisted  = "unlisted"
+)
+
+// Kinds of posts. Reposts share another post as is, quotes add their own text.
+const (
+	PostKindPost   = "post"
+	PostKindRepost = "repost"
+	PostKindQuote  = "quote"
+)
+
+// Post is a post of any kind. CreatedAt is the time it went public, which for
+// scheduled posts is set when they get published.
 type Post struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Version   int       `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      User      `json:"user "`
+	ID             int64        `json:"id"`
+	Title          string       `json:"title"`
+	Content        string       `json:"content"`
+	UserID         int64        `json:"user_id"`
+	Tags           []string     `json:"tags"`
+	CreatedAt      string       `json:"created_at"`
+	UpdatedAt      string       `json:"updated_at"`
+	Version        int          `json:"version"`
+	Status         string       `json:"status"`
+	Visibility     string       `json:"visibility"`
+	PublishAt      *string      `json:"publish_at"`
+	EditedAt       *string      `json:"edited_at"`
+	Attachments    []Attachment `json:"attachments"`
+	Poll           *Poll        `json:"poll,omitempty"`
+	Entities       []PostEntity `json:"entities"`
+	Kind           string       `json:"kind"`
+	OriginalPostID *int64       `json:"original_post_id"`
+	// Original is the reposted or quoted post, OriginalDeleted marks a share
+	// whose original has since been deleted or hidden from the viewer
+	Original        *Post `json:"original,omitempty"`
+	OriginalDeleted bool  `json:"original_deleted,omitempty"`
+	User            User  `json:"user "`
+	ReactionSummary
+}
+
+// VisibleTo reports whether the post can be opened by viewerID, following
+// telling whether the viewer follows the author.
+func (p *Post) VisibleTo(viewerID int64, following bool) bool {
+	if p.UserID == viewerID {
+		return true
+	}
+
+	if p.Status != PostStatusPublished {
+		return false
+	}
+
+	switch p.Visibility {
+	case PostVisibilityPublic, PostVisibilityUnlisted:
+		return true
+	case PostVisibilityFollowers:
+		return following
+	default:
+		return false
+	}
+}
+
+// visiblePostSQL is VisibleTo as an SQL condition on the post aliased alias,
+// viewer being the placeholder of the viewer's id. Listed leaves unlisted
+// posts of other users out.
+func visiblePostSQL(alias, viewer string, listed bool) string {
+	visibilities := `'public', 'unlisted'`
+	if listed {
+		visibilities = `'public'`
+	}
+
+	return `(` + alias + `.user_id = ` + viewer + ` OR (` + alias + `.status = 'published' AND (` +
+		alias + `.visibility IN (` + visibilities + `) OR (` + alias + `.visibility = 'followers' AND EXISTS (
+			SELECT 1 FROM followers vf WHERE vf.user_id = ` + alias + `.user_id AND vf.follower_id = ` + viewer + `
+		)))))`
 }
 
 type PostWithMetadata struct {
@@ -30,87 +105,334 @@ type PostStore struct {
 	db *sql.DB
 }
 
+// postGroupSQL is the post a post aliased alias stands for: the original of a
+// repost, the post itself otherwise.
+func postGroupSQL(alias string) string {
+	return `COALESCE(CASE WHEN ` + alias + `.kind = 'repost' THEN ` + alias + `.original_post_id END, ` + alias + `.id)`
+}
+
+// postListColumns are the columns of a post aliased p, joined with its author
+// aliased u, as read by scanPostWithMetadata. Queries selecting more columns
+// put them after these.
+const postListColumns = `
+	p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.status, p.edited_at,
+	p.visibility, p.tags, p.kind, p.original_post_id, u.username,
+	(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comments_count
+`
+
+// scanPostWithMetadata reads postListColumns into post, and the columns
+// selected after them into extra.
+func scanPostWithMetadata(rows *sql.Rows, post *PostWithMetadata, extra ...any) error {
+	return rows.Scan(append([]any{
+		&post.ID,
+		&post.UserID,
+		&post.Title,
+		&post.Content,
+		&post.CreatedAt,
+		&post.Version,
+		&post.Status,
+		&post.EditedAt,
+		&post.Visibility,
+		pq.Array(&post.Tags),
+		&post.Kind,
+		&post.OriginalPostID,
+		&post.User.Username,
+		&post.CommentsCount,
+	}, extra...)...)
+}
+
+// GetUserFeed returns a page of the published posts, reposts and quotes of the
+// user and of the users they follow that the user may see, created since
+// fq.Since (inclusive) and until fq.Until (exclusive) when set. An original
+// reposted several times (or also posted by someone followed) only shows up
+// once, as its most recent appearance.
 func (store *PostStore) GetUserFeed(ctx context.Context, id int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return store.feed(ctx, id, fq, userFeedSource)
+}
+
+// userFeedSource selects the posts of the user and of the users they follow.
+const userFeedSource = `(p.user_id = $1 OR EXISTS (
+	SELECT 1 FROM followers f WHERE f.user_id = p.user_id AND f.follower_id = $1
+))`
+
+// feed returns a page of the feed of the user made of the posts matching the
+// source condition, whose arguments are bound from $9 on.
+func (store *PostStore) feed(ctx context.Context, id int64, fq PaginatedFeedQuery, source string, sourceArgs ...any) ([]PostWithMetadata, error) {
+	feed, err := store.feedPosts(ctx, id, fq, source, sourceArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]*Post, len(feed))
+	for i := range feed {
+		posts[i] = &feed[i].Post
+	}
+
+	if err := attachDetails(ctx, store.db, posts, id); err != nil {
+		return nil, err
+	}
+
+	return feed, nil
+}
+
+// feedPosts is feed without the details of the posts.
+//
+// A post and its reposts show once, as the latest of them. The page bound is
+// applied before they are grouped so that only the rows past the cursor are
+// scanned. Walking back in time, that leaves out the latest rows of the
+// groups shown on earlier pages, so the groups with a row at or after the
+// cursor are dropped.
+func (store *PostStore) feedPosts(ctx context.Context, id int64, fq PaginatedFeedQuery, source string, sourceArgs ...any) ([]PostWithMetadata, error) {
+	bound, _, pageArgs := fq.keyset("p.created_at", "p.id", 6)
+	_, page, _ := fq.keyset("created_at", "id", 6)
+
+	filter := source + ` AND
+		p.status = 'published' AND
+		` + visiblePostSQL("p", "$1", true) + ` AND
+		(p.kind <> 'repost' OR ` + visiblePostSQL("o", "$1", true) + `) AND
+		($4::timestamptz IS NULL OR p.created_at >= $4) AND
+		($5::timestamptz IS NULL OR p.created_at < $5) AND
+		(p.title ILIKE '%' || $2 || '%' OR p.content ILIKE '%' || $2 || '%'
+			OR o.title ILIKE '%' || $2 || '%' OR o.content ILIKE '%' || $2 || '%') AND
+		((CASE WHEN p.kind = 'repost' THEN o.tags ELSE p.tags END) @> $3 OR $3 = '{}')`
+
+	shown := "TRUE"
+	if fq.Cursor != nil && fq.descending() {
+		shown = `NOT EXISTS (
+			SELECT 1 FROM posts p
+			LEFT JOIN posts o ON o.id = p.original_post_id
+			WHERE ` + postGroupSQL("p") + ` = ` + postGroupSQL("feed") + ` AND
+				` + filter + ` AND NOT ` + bound + `
+		)`
+	}
+
 	query := `
-		SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.version, 
-			p.tags, u.username, COUNT(c.id) AS comments_count
-		FROM posts p
-		LEFT JOIN comments C ON c.post_id = p.id
-		LEFT JOIN users u ON p.user_id = u.id
-		JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
-		WHERE 
-			f.user_id = $1  AND
-			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND (p.tags @> $5 OR $5 = '{}')
-		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + fq.Sort + `
-		LIMIT $2 OFFSET $3
-	`
+		SELECT * FROM (
+			SELECT DISTINCT ON (` + postGroupSQL("p") + `)
+				` + postListColumns + `
+			FROM posts p
+			LEFT JOIN posts o ON o.id = p.original_post_id
+			LEFT JOIN users u ON p.user_id = u.id
+			WHERE ` + filter + ` AND ` + bound + `
+			ORDER BY ` + postGroupSQL("p") + `, p.created_at DESC, p.id DESC
+		) feed
+		WHERE ` + shown + page
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := store.db.QueryContext(ctx, query, id, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags))
+	args := append([]any{
+		id,
+		fq.Search,
+		pq.Array(fq.Tags),
+		sql.NullString{String: fq.Since, Valid: fq.Since != ""},
+		sql.NullString{String: fq.Until, Valid: fq.Until != ""},
+	}, pageArgs...)
+	args = append(args, sourceArgs...)
+
+	rows, err := store.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	var feed []PostWithMetadata
+	feed := []PostWithMetadata{}
 	for rows.Next() {
 		var post PostWithMetadata
-		err := rows.Scan(
-			&post.ID,
-			&post.UserID,
-			&post.Title,
-			&post.Content,
-			&post.CreatedAt,
-			&post.Version,
-			pq.Array(&post.Tags),
-			&post.User.Username,
-			&post.CommentsCount,
-		)
-		if err != nil {
+		if err := scanPostWithMetadata(rows, &post); err != nil {
 			return nil, err
 		}
 
 		feed = append(feed, post)
 	}
-	return feed, nil
+
+	return feed, rows.Err()
 }
 
-func (store *PostStore) Create(ctx context.Context, post *Post) error {
-	query := `
-		INSERT INTO posts (title, content, user_id, tags)
-		VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at
-	`
+// AttachDetails embeds the originals of reposts and quotes and loads the
+// attachments, polls, entities and reactions of the posts, as seen by
+// viewerID.
+func (store *PostStore) AttachDetails(ctx context.Context, posts []*Post, viewerID int64) error {
+	return attachDetails(ctx, store.db, posts, viewerID)
+}
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+// attachDetails embeds the originals of reposts and quotes and loads the
+// attachments, polls, entities and reactions of every post involved, as seen
+// by viewerID.
+func attachDetails(ctx context.Context, db *sql.DB, posts []*Post, viewerID int64) error {
+	var originalIDs []int64
+	for _, post := range posts {
+		if post.OriginalPostID != nil {
+			originalIDs = append(originalIDs, *post.OriginalPostID)
+		}
+	}
 
-	err := store.db.QueryRowContext(ctx, query,
-		post.Title,
-		post.Content,
-		post.UserID,
-		pq.Array(post.Tags),
-	).Scan(
-		&post.ID,
-		&post.CreatedAt,
-		&post.UpdatedAt,
-	)
+	originals := map[int64]*Post{}
+	if len(originalIDs) > 0 {
+		query := `
+			SELECT p.id, p.user_id, p.title, p.content, p.tags, p.created_at, p.updated_at, p.version,
+				p.status, p.visibility, p.edited_at, p.kind, u.id, u.username
+			FROM posts p
+			JOIN users u ON u.id = p.user_id
+			WHERE p.id = ANY($1) AND ` + visiblePostSQL("p", "$2", false) + `
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		rows, err := db.QueryContext(ctx, query, pq.Array(originalIDs), viewerID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			original := &Post{}
+			err := rows.Scan(
+				&original.ID,
+				&original.UserID,
+				&original.Title,
+				&original.Content,
+				pq.Array(&original.Tags),
+				&original.CreatedAt,
+				&original.UpdatedAt,
+				&original.Version,
+				&original.Status,
+				&original.Visibility,
+				&original.EditedAt,
+				&original.Kind,
+				&original.User.ID,
+				&original.User.Username,
+			)
+			if err != nil {
+				return err
+			}
+
+			originals[original.ID] = original
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
 
+	ids := make([]int64, 0, len(posts)+len(originals))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	for id := range originals {
+		ids = append(ids, id)
+	}
+
+	summaries, err := reactionSummaries(ctx, db, ReactionTargetPost, ids, viewerID)
+	if err != nil {
+		return err
+	}
+
+	attachments, err := postAttachments(ctx, db, ids)
+	if err != nil {
+		return err
+	}
+
+	polls, err := postPolls(ctx, db, ids, viewerID)
 	if err != nil {
 		return err
 	}
 
+	all := append(make([]*Post, 0, len(posts)+len(originals)), posts...)
+	for _, original := range originals {
+		all = append(all, original)
+	}
+
+	entities, err := postEntities(ctx, db, all)
+	if err != nil {
+		return err
+	}
+
+	for _, original := range originals {
+		original.ReactionSummary = *summaries[original.ID]
+		original.Attachments = attachments[original.ID]
+		original.Poll = polls[original.ID]
+		original.Entities = entities[original.ID]
+	}
+
+	for _, post := range posts {
+		post.ReactionSummary = *summaries[post.ID]
+		post.Attachments = attachments[post.ID]
+		post.Poll = polls[post.ID]
+		post.Entities = entities[post.ID]
+
+		if post.OriginalPostID != nil {
+			post.Original = originals[*post.OriginalPostID]
+		}
+		post.OriginalDeleted = post.Kind != PostKindPost && post.Original == nil
+	}
+
 	return nil
 }
 
+func (store *PostStore) Create(ctx context.Context, post *Post) error {
+	query := `
+		INSERT INTO posts (title, content, user_id, tags, kind, original_post_id, status, publish_at, visibility)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at
+	`
+
+	if post.Kind == "" {
+		post.Kind = PostKindPost
+	}
+	if post.Status == "" {
+		post.Status = PostStatusPublished
+	}
+	if post.Visibility == "" {
+		post.Visibility = PostVisibilityPublic
+	}
+
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(ctx, query,
+			post.Title,
+			post.Content,
+			post.UserID,
+			pq.Array(post.Tags),
+			post.Kind,
+			post.OriginalPostID,
+			post.Status,
+			post.PublishAt,
+			post.Visibility,
+		).Scan(
+			&post.ID,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+		)
+
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "idx_posts_unique_repost"`:
+				return ErrorConflict
+			default:
+				return err
+			}
+		}
+
+		if post.Poll != nil {
+			if err := createPoll(ctx, tx, post.ID, post.Poll); err != nil {
+				return err
+			}
+		}
+
+		return syncEntities(ctx, tx, post)
+	})
+}
+
 func (store *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 	var post Post
 
 	query := `
-		SELECT id, user_id, title, content, tags, created_at, updated_at, version FROM posts
+		SELECT id, user_id, title, content, tags, created_at, updated_at, version, status, publish_at,
+			visibility, edited_at, kind, original_post_id
+		FROM posts
 		WHERE id=$1
 	`
 
@@ -126,6 +448,12 @@ func (store *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		&post.Version,
+		&post.Status,
+		&post.PublishAt,
+		&post.Visibility,
+		&post.EditedAt,
+		&post.Kind,
+		&post.OriginalPostID,
 	)
 
 	if err != nil {
@@ -141,34 +469,292 @@ func (store *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 	return &post, nil
 }
 
-func (store *PostStore) Update(ctx context.Context, post *Post) error {
+// Update saves the post if it is still at the version it was read at, keeping
+// the previous title, content and tags as a revision.
+func (store *PostStore) Update(ctx context.Context, post *Post, editorID int64) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		if err := store.createRevision(ctx, tx, post, editorID); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE posts
+			SET title = $1, content = $2, tags = $3, status = $6, publish_at = $7, visibility = $8,
+				version = version + 1, updated_at = NOW(),
+				edited_at = CASE WHEN status = 'published' THEN NOW() ELSE edited_at END,
+				created_at = CASE WHEN status <> 'published' AND $6 = 'published' THEN NOW() ELSE created_at END
+			WHERE id = $4 AND version = $5
+			RETURNING version, created_at, updated_at, edited_at;
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(ctx, query,
+			post.Title,
+			post.Content,
+			pq.Array(post.Tags),
+			post.ID,
+			post.Version,
+			post.Status,
+			post.PublishAt,
+			post.Visibility,
+		).Scan(
+			&post.Version,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.EditedAt,
+		)
+
+		if err != nil {
+			switch {
+			// the revision was taken so the post exists, its version moved on
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrorEditConflict
+
+			default:
+				return err
+			}
+
+		}
+
+		return syncEntities(ctx, tx, post)
+	})
+}
+
+// createRevision copies the stored post before it is overwritten. Edits made
+// before a post is published are not part of its history. A version that has
+// moved on since the post was read is reported as ErrorEditConflict.
+func (store *PostStore) createRevision(ctx context.Context, tx *sql.Tx, post *Post, editorID int64) error {
 	query := `
-		UPDATE posts
-		SET title = $1, content = $2, tags = $3, version = version + 1
-		WHERE id = $4 AND version = $5
-		RETURNING version;
+		INSERT INTO post_revisions (post_id, version, title, content, tags, edited_by)
+		SELECT id, version, title, content, tags, $3 FROM posts
+		WHERE id = $1 AND version = $2 AND status = 'published'
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := store.db.QueryRowContext(ctx, query, post.Title, post.Content, pq.Array(post.Tags), post.ID, post.Version).Scan(&post.Version)
+	res, err := tx.ExecContext(ctx, query, post.ID, post.Version, editorID)
+	if err != nil {
+		switch {
+		// a concurrent update of the same version got there first
+		case err.Error() == `pq: duplicate key value violates unique constraint "post_revisions_post_id_version_key"`:
+			return ErrorEditConflict
+		default:
+			return err
+		}
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		var version int
+		err := tx.QueryRowContext(ctx, `SELECT version FROM posts WHERE id = $1`, post.ID).Scan(&version)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+
+		// unpublished, there was nothing to keep
+		if version != post.Version {
+			return ErrorEditConflict
+		}
+	}
+
+	return nil
+}
+
+func (store *PostStore) Delete(ctx context.Context, id int64) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		// plain reposts have nothing left to show, quotes are kept as
+		// tombstones by the foreign key
+		if err := store.deleteReposts(ctx, tx, id); err != nil {
+			return err
+		}
+
+		// reactions are not tied to their target by a foreign key
+		if err := deletePostReactions(ctx, tx, id); err != nil {
+			return err
+		}
+
+		return store.delete(ctx, tx, id)
+	})
+}
+
+// Repost shares a post as is. Reposting a repost shares its original instead.
+func (store *PostStore) Repost(ctx context.Context, post *Post, original *Post) error {
+	post.Kind = PostKindRepost
+	post.OriginalPostID = rootPostID(original)
+
+	return store.Create(ctx, post)
+}
+
+// Quote shares a post with the user's own text on top.
+func (store *PostStore) Quote(ctx context.Context, post *Post, original *Post) error {
+	post.Kind = PostKindQuote
+	post.OriginalPostID = rootPostID(original)
+
+	return store.Create(ctx, post)
+}
+
+// DeleteRepost undoes the user's repost of a post.
+func (store *PostStore) DeleteRepost(ctx context.Context, userID int64, original *Post) error {
+	query := `SELECT id FROM posts WHERE user_id = $1 AND original_post_id = $2 AND kind = $3`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
 
+	var id int64
+	err := store.db.QueryRowContext(ctx, query, userID, *rootPostID(original), PostKindRepost).Scan(&id)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrorNotFound
-
 		default:
 			return err
 		}
+	}
+
+	return store.Delete(ctx, id)
+}
+
+// ListUnpublished returns a page of the user's drafts and scheduled posts.
+func (store *PostStore) ListUnpublished(ctx context.Context, userID int64, cq CursorQuery) ([]Post, error) {
+	keyset, page, args := cq.keyset("created_at", "id", 2)
+
+	query := `
+		SELECT id, user_id, title, content, tags, created_at, updated_at, version, status, publish_at, visibility
+		FROM posts
+		WHERE user_id = $1 AND status <> 'published' AND ` + keyset + page
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
 
+	rows, err := store.db.QueryContext(ctx, query, append([]any{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		post := Post{Kind: PostKindPost}
+		err := rows.Scan(
+			&post.ID,
+			&post.UserID,
+			&post.Title,
+			&post.Content,
+			pq.Array(&post.Tags),
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.Version,
+			&post.Status,
+			&post.PublishAt,
+			&post.Visibility,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// PublishDue publishes up to limit scheduled posts whose time has come and
+// returns their ids. Rows locked by another instance are skipped, and a crash
+// before the commit leaves the posts scheduled for the next run, so every post
+// gets published at least once without instances waiting on each other.
+func (store *PostStore) PublishDue(ctx context.Context, limit int) ([]int64, error) {
+	query := `
+		UPDATE posts
+		SET status = 'published', created_at = publish_at, updated_at = NOW(), version = version + 1
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE status = 'scheduled' AND publish_at <= NOW()
+			ORDER BY publish_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (store *PostStore) deleteReposts(ctx context.Context, tx *sql.Tx, originalID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM posts WHERE original_post_id = $1 AND kind = $2`, originalID, PostKindRepost)
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := deletePostReactions(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if err := store.delete(ctx, tx, id); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (store *PostStore) Delete(ctx context.Context, id int64) error {
+// rootPostID is the post a repost points to, or the post itself.
+func rootPostID(post *Post) *int64 {
+	if post.Kind == PostKindRepost && post.OriginalPostID != nil {
+		return post.OriginalPostID
+	}
+
+	return &post.ID
+}
+
+func (store *PostStore) delete(ctx context.Context, tx *sql.Tx, id int64) error {
 	query := `
 		DELETE FROM posts
 		WHERE id = $1;
@@ -177,7 +763,7 @@ func (store *PostStore) Delete(ctx context.Context, id int64) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	res, err := store.db.ExecContext(ctx, query, id)
+	res, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,520 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetUserFeed(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	followers := &FollowerStore{db}
+	// alice follows bob, carol follows alice
+	if err := followers.Follow(ctx, alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := followers.Follow(ctx, carol, alice); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := func(userID int64, title string, at time.Time, opts ...func(*Post)) int64 {
+		p := &Post{UserID: userID, Title: title, Content: title, Tags: []string{}}
+		for _, opt := range opts {
+			opt(p)
+		}
+		return createTestPost(t, db, p, at).ID
+	}
+	withVisibility := func(visibility string) func(*Post) {
+		return func(p *Post) { p.Visibility = visibility }
+	}
+
+	aliceOld := post(alice, "alice old", day)
+	bobNew := post(bob, "bob new", day.Add(2*time.Hour))
+	bobFollowers := post(bob, "bob followers", day.Add(time.Hour), withVisibility(PostVisibilityFollowers))
+	bobPrivate := post(bob, "bob private", day.Add(30*time.Minute), withVisibility(PostVisibilityPrivate))
+	bobUnlisted := post(bob, "bob unlisted", day.Add(15*time.Minute), withVisibility(PostVisibilityUnlisted))
+	post(alice, "alice draft", day.Add(3*time.Hour), func(p *Post) { p.Status = PostStatusDraft })
+	post(carol, "carol", day.Add(time.Hour))
+
+	tests := []struct {
+		name   string
+		userID int64
+		fq     PaginatedFeedQuery
+		want   []int64
+	}{
+		{
+			name:   "own and followed posts",
+			userID: alice,
+			fq:     PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 20, Sort: "desc"}},
+			want:   []int64{bobNew, bobFollowers, aliceOld},
+		},
+		{
+			name:   "ascending",
+			userID: alice,
+			fq:     PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 20, Sort: "asc"}},
+			want:   []int64{aliceOld, bobFollowers, bobNew},
+		},
+		{
+			name:   "since is inclusive",
+			userID: alice,
+			fq:     PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 20, Sort: "desc"}, Since: "2024-01-01T01:00:00Z"},
+			want:   []int64{bobNew, bobFollowers},
+		},
+		{
+			name:   "until is exclusive",
+			userID: alice,
+			fq:     PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 20, Sort: "desc"}, Until: "2024-01-01T02:00:00Z"},
+			want:   []int64{bobFollowers, aliceOld},
+		},
+		{
+			name:   "window",
+			userID: alice,
+			fq:     PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 20, Sort: "desc"}, Since: "2024-01-01T00:30:00Z", Until: "2024-01-01T01:30:00Z"},
+			want:   []int64{bobFollowers},
+		},
+		{
+			name:   "followed users do not see their followers' posts",
+			userID: bob,
+			fq:     PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 20, Sort: "desc"}},
+			want:   []int64{bobNew, bobFollowers, bobPrivate, bobUnlisted},
+		},
+	}
+
+	store := &PostStore{db}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := store.GetUserFeed(ctx, tt.userID, tt.fq)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int64
+			for _, post := range feed {
+				got = append(got, post.ID)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got posts %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUserFeedCursor(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+
+	// posts at the same time are ordered by id
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var want []int64
+	for i := range 5 {
+		p := createTestPost(t, db, &Post{UserID: alice, Title: "post", Content: "post", Tags: []string{}}, day.Add(time.Duration(i/2)*time.Hour))
+		want = append([]int64{p.ID}, want...)
+	}
+
+	store := &PostStore{db}
+	fq := PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 2, Sort: "desc"}}
+
+	var got []int64
+	for range want {
+		rows, err := store.GetUserFeed(ctx, alice, fq)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, next, _ := Paginate(rows, fq.CursorQuery, func(post PostWithMetadata) (string, int64) {
+			return post.CreatedAt, post.ID
+		})
+		for _, post := range page {
+			got = append(got, post.ID)
+		}
+
+		if len(got) == 2 {
+			// new posts do not shift the following pages
+			createTestPost(t, db, &Post{UserID: alice, Title: "new", Content: "new", Tags: []string{}}, day.Add(time.Hour*24))
+		}
+
+		if next == nil {
+			break
+		}
+		fq.Cursor = next
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("got posts %v, expected %v", got, want)
+	}
+}
+
+func TestGetUserFeedCursorReposts(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	if err := (&FollowerStore{db}).Follow(ctx, alice, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := func(userID int64, at time.Time, opts ...func(*Post)) int64 {
+		p := &Post{UserID: userID, Title: "post", Content: "post", Tags: []string{}}
+		for _, opt := range opts {
+			opt(p)
+		}
+		return createTestPost(t, db, p, at).ID
+	}
+
+	original := post(bob, day)
+	first := post(bob, day.Add(time.Hour))
+	second := post(bob, day.Add(2*time.Hour))
+	// the original shows as the repost, and only once
+	repost := post(alice, day.Add(3*time.Hour), func(p *Post) {
+		p.Kind = PostKindRepost
+		p.OriginalPostID = &original
+	})
+
+	store := &PostStore{db}
+
+	walk := func(sort string) []int64 {
+		t.Helper()
+
+		fq := PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 1, Sort: sort}}
+
+		var got []int64
+		for range 10 {
+			rows, err := store.GetUserFeed(ctx, alice, fq)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			page, next, _ := Paginate(rows, fq.CursorQuery, func(post PostWithMetadata) (string, int64) {
+				return post.CreatedAt, post.ID
+			})
+			for _, post := range page {
+				got = append(got, post.ID)
+			}
+
+			if next == nil {
+				break
+			}
+			fq.Cursor = next
+		}
+
+		return got
+	}
+
+	if got, want := walk("desc"), []int64{repost, second, first}; !slices.Equal(got, want) {
+		t.Errorf("got posts %v walking back, expected %v", got, want)
+	}
+	if got, want := walk("asc"), []int64{first, second, repost}; !slices.Equal(got, want) {
+		t.Errorf("got posts %v walking forward, expected %v", got, want)
+	}
+}
+
+func TestPublishDue(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedule := func(status string, publishAt time.Time) int64 {
+		p := &Post{UserID: alice, Title: "post", Content: "post", Tags: []string{}, Status: status}
+		if status == PostStatusScheduled {
+			at := publishAt.Format(time.RFC3339)
+			p.PublishAt = &at
+		}
+		return createTestPost(t, db, p, day).ID
+	}
+
+	due := schedule(PostStatusScheduled, time.Now().Add(-time.Hour))
+	locked := schedule(PostStatusScheduled, time.Now().Add(-time.Minute))
+	later := schedule(PostStatusScheduled, time.Now().Add(time.Hour))
+	draft := schedule(PostStatusDraft, time.Time{})
+
+	// another instance is publishing the locked post
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT id FROM posts WHERE id = $1 FOR UPDATE`, locked); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &PostStore{db}
+
+	published, err := store.PublishDue(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{due}; !slices.Equal(published, want) {
+		t.Errorf("got published posts %v while one is locked, expected %v", published, want)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	published, err = store.PublishDue(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{locked}; !slices.Equal(published, want) {
+		t.Errorf("got published posts %v once unlocked, expected %v", published, want)
+	}
+
+	for id, status := range map[int64]string{due: PostStatusPublished, locked: PostStatusPublished, later: PostStatusScheduled, draft: PostStatusDraft} {
+		post, err := store.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.Status != status {
+			t.Errorf("got status %q for post %d, expected %q", post.Status, id, status)
+		}
+	}
+
+	// published posts are dated from their publication
+	p, err := store.GetByID(ctx, due)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.CreatedAt != *p.PublishAt {
+		t.Errorf("got created_at %s, expected the publish_at %s", p.CreatedAt, *p.PublishAt)
+	}
+}
+
+func TestUnpublishedPostsHidden(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	if err := (&FollowerStore{db}).Follow(ctx, bob, alice); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	publishAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	posts := &PostStore{db}
+	bookmarks := &BookmarkStore{db}
+
+	for _, status := range []string{PostStatusDraft, PostStatusScheduled} {
+		t.Run(status, func(t *testing.T) {
+			p := &Post{UserID: alice, Title: status, Content: status, Tags: []string{}, Status: status}
+			if status == PostStatusScheduled {
+				p.PublishAt = &publishAt
+			}
+			id := createTestPost(t, db, p, day).ID
+
+			post, err := posts.GetByID(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !post.VisibleTo(alice, false) {
+				t.Error("expected the author to see the post")
+			}
+			if post.VisibleTo(bob, true) {
+				t.Error("expected followers not to see the post")
+			}
+
+			feed, err := posts.GetUserFeed(ctx, bob, PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 20, Sort: "desc"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range feed {
+				if p.ID == id {
+					t.Error("expected the post to be left out of the feed of followers")
+				}
+			}
+
+			collection := &BookmarkCollection{UserID: bob, Name: status}
+			if err := bookmarks.CreateCollection(ctx, collection); err != nil {
+				t.Fatal(err)
+			}
+			if err := bookmarks.Add(ctx, bob, collection.ID, id); err != ErrorNotFound {
+				t.Errorf("got %v bookmarking the post, expected %v", err, ErrorNotFound)
+			}
+		})
+	}
+}
+
+func TestPostVisibleTo(t *testing.T) {
+	const author, viewer = 1, 2
+
+	tests := []struct {
+		name       string
+		status     string
+		visibility string
+		viewerID   int64
+		following  bool
+		want       bool
+	}{
+		{"public", PostStatusPublished, PostVisibilityPublic, viewer, false, true},
+		{"unlisted", PostStatusPublished, PostVisibilityUnlisted, viewer, false, true},
+		{"followers only to a follower", PostStatusPublished, PostVisibilityFollowers, viewer, true, true},
+		{"followers only to anyone else", PostStatusPublished, PostVisibilityFollowers, viewer, false, false},
+		{"private to a follower", PostStatusPublished, PostVisibilityPrivate, viewer, true, false},
+		{"private to the author", PostStatusPublished, PostVisibilityPrivate, author, false, true},
+		{"draft to a follower", PostStatusDraft, PostVisibilityPublic, viewer, true, false},
+		{"draft to the author", PostStatusDraft, PostVisibilityPublic, author, false, true},
+		{"scheduled to a follower", PostStatusScheduled, PostVisibilityPublic, viewer, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &Post{UserID: author, Status: tt.status, Visibility: tt.visibility}
+
+			if got := post.VisibleTo(tt.viewerID, tt.following); got != tt.want {
+				t.Errorf("got %t, expected %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostVisibilityListings(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+	dave := createTestUser(t, db, "dave")
+
+	followers := &FollowerStore{db}
+	// dave follows bob, carol only follows dave
+	if err := followers.Follow(ctx, dave, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := followers.Follow(ctx, carol, dave); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := func(userID int64, content, visibility string, at time.Time) int64 {
+		p := &Post{UserID: userID, Title: content, Content: content, Tags: []string{}, Visibility: visibility}
+		return createTestPost(t, db, p, at).ID
+	}
+
+	public := post(bob, "hi @carol #go", PostVisibilityPublic, day)
+	followersOnly := post(bob, "hi @carol #go", PostVisibilityFollowers, day.Add(time.Hour))
+	private := post(bob, "hi @carol #go", PostVisibilityPrivate, day.Add(2*time.Hour))
+	unlisted := post(bob, "hi @carol #go", PostVisibilityUnlisted, day.Add(3*time.Hour))
+
+	// dave reposts a public post, which bob then restricts to his followers
+	shared := post(bob, "shared", PostVisibilityPublic, day.Add(4*time.Hour))
+	repost := createTestPost(t, db, &Post{UserID: dave, Tags: []string{}, Kind: PostKindRepost, OriginalPostID: &shared}, day.Add(5*time.Hour)).ID
+	if _, err := db.Exec(`UPDATE posts SET visibility = $1 WHERE id = $2`, PostVisibilityFollowers, shared); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &PostStore{db}
+	cq := CursorQuery{Limit: 20, Sort: "desc"}
+
+	ids := func(posts []PostWithMetadata) []int64 {
+		var ids []int64
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name string
+		list func() ([]PostWithMetadata, error)
+		want []int64
+	}{
+		{
+			name: "feed of a follower",
+			list: func() ([]PostWithMetadata, error) {
+				return store.GetUserFeed(ctx, dave, PaginatedFeedQuery{CursorQuery: cq})
+			},
+			want: []int64{repost, followersOnly, public},
+		},
+		{
+			name: "feed of a follower of a follower",
+			list: func() ([]PostWithMetadata, error) {
+				return store.GetUserFeed(ctx, carol, PaginatedFeedQuery{CursorQuery: cq})
+			},
+			want: nil,
+		},
+		{
+			name: "hashtag for a follower",
+			list: func() ([]PostWithMetadata, error) { return store.ListByHashtag(ctx, "go", dave, cq) },
+			want: []int64{followersOnly, public},
+		},
+		{
+			name: "hashtag for anyone else",
+			list: func() ([]PostWithMetadata, error) { return store.ListByHashtag(ctx, "go", carol, cq) },
+			want: []int64{public},
+		},
+		{
+			name: "mentions of anyone else",
+			list: func() ([]PostWithMetadata, error) { return store.ListMentioning(ctx, carol, cq) },
+			want: []int64{unlisted, public},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, err := tt.list()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := ids(posts); !slices.Equal(got, tt.want) {
+				t.Errorf("got posts %v, expected %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("bookmarks of a former follower", func(t *testing.T) {
+		bookmarks := &BookmarkStore{db}
+
+		if err := followers.Follow(ctx, carol, bob); err != nil {
+			t.Fatal(err)
+		}
+
+		collection := &BookmarkCollection{UserID: carol, Name: "saved"}
+		if err := bookmarks.CreateCollection(ctx, collection); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []int64{public, followersOnly} {
+			if err := bookmarks.Add(ctx, carol, collection.ID, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := bookmarks.Add(ctx, carol, collection.ID, private); err != ErrorNotFound {
+			t.Errorf("got %v bookmarking a private post, expected %v", err, ErrorNotFound)
+		}
+
+		if err := followers.Unfollow(ctx, carol, bob); err != nil {
+			t.Fatal(err)
+		}
+
+		saved, err := bookmarks.ListPosts(ctx, carol, collection.ID, cq)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []int64
+		for _, post := range saved {
+			got = append(got, post.ID)
+		}
+		if want := []int64{public}; !slices.Equal(got, want) {
+			t.Errorf("got bookmarked posts %v, expected %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Affinity measures how close a viewer is to an author: whether they follow
+// them and how many of the author's posts they recently commented on or
+// reacted to.
+type Affinity struct {
+	Follows      bool
+	Interactions int
+}
+
+// FeedCandidate is a post of a feed considered for ranking, with its comments
+// and reactions counted as of the time of the ranking.
+type FeedCandidate struct {
+	PostWithMetadata
+	Comments  int
+	Reactions int
+}
+
+// GetFeedCandidates returns up to limit of the latest posts of the feed of
+// the user matching the filters of the query, for ranking at the given time.
+// Comments and reactions made since are not counted, so that ranking again at
+// the same time gives the same counts.
+func (store *PostStore) GetFeedCandidates(ctx context.Context, id int64, fq PaginatedFeedQuery, limit int, at time.Time) ([]FeedCandidate, error) {
+	fq.CursorQuery = CursorQuery{Limit: limit, Sort: "desc"}
+
+	feed, err := store.feedPosts(ctx, id, fq, userFeedSource)
+	if err != nil {
+		return nil, err
+	}
+	if len(feed) > limit {
+		feed = feed[:limit]
+	}
+
+	candidates := make([]FeedCandidate, len(feed))
+	index := make(map[int64]int, len(feed))
+	ids := make([]int64, len(feed))
+	for i := range feed {
+		candidates[i].PostWithMetadata = feed[i]
+		index[feed[i].ID] = i
+		ids[i] = feed[i].ID
+	}
+
+	if len(ids) == 0 {
+		return candidates, nil
+	}
+
+	query := `
+		SELECT t.id,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = t.id AND c.created_at <= $2),
+			(
+				SELECT COUNT(*) FROM reactions r
+				WHERE r.target_type = 'post' AND r.target_id = t.id AND r.created_at <= $2
+			)
+		FROM unnest($1::bigint[]) AS t(id)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, pq.Array(ids), at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			postID              int64
+			comments, reactions int
+		)
+		if err := rows.Scan(&postID, &comments, &reactions); err != nil {
+			return nil, err
+		}
+
+		candidate := &candidates[index[postID]]
+		candidate.Comments = comments
+		candidate.Reactions = reactions
+	}
+
+	return candidates, rows.Err()
+}
+
+// Affinities returns the affinity of the viewer with each author, counting
+// the interactions between since and until.
+func (store *PostStore) Affinities(ctx context.Context, viewerID int64, authorIDs []int64, since, until time.Time) (map[int64]Affinity, error) {
+	query := `
+		SELECT a.id,
+			EXISTS (SELECT 1 FROM followers f WHERE f.user_id = a.id AND f.follower_id = $1),
+			(
+				SELECT COUNT(*) FROM comments c
+				JOIN posts p ON p.id = c.post_id
+				WHERE p.user_id = a.id AND c.user_id = $1 AND c.created_at >= $3 AND c.created_at < $4
+			) + (
+				SELECT COUNT(*) FROM reactions r
+				JOIN posts p ON p.id = r.target_id
+				WHERE r.target_type = 'post' AND p.user_id = a.id AND r.user_id = $1 AND r.created_at >= $3 AND r.created_at < $4
+			)
+		FROM unnest($2::bigint[]) AS a(id)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, viewerID, pq.Array(authorIDs), since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	affinities := make(map[int64]Affinity, len(authorIDs))
+	for rows.Next() {
+		var (
+			authorID int64
+			affinity Affinity
+		)
+		if err := rows.Scan(&authorID, &affinity.Follows, &affinity.Interactions); err != nil {
+			return nil, err
+		}
+
+		affinities[authorID] = affinity
+	}
+
+	return affinities, rows.Err()
+}
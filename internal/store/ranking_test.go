@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetFeedCandidates(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	if err := (&FollowerStore{db}).Follow(ctx, alice, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	post := createTestPost(t, db, &Post{UserID: bob, Title: "bob", Content: "bob", Tags: []string{}}, at.Add(-time.Hour))
+
+	// a comment and a reaction before the ranking, the same after it
+	for _, createdAt := range []time.Time{at.Add(-time.Minute), at.Add(time.Minute)} {
+		if _, err := db.Exec(`INSERT INTO comments (user_id, post_id, content, created_at) VALUES ($1, $2, 'hi', $3)`, alice, post.ID, createdAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, createdAt := range []time.Time{at.Add(-time.Minute), at.Add(time.Minute)} {
+		if _, err := db.Exec(`INSERT INTO reactions (user_id, target_type, target_id, kind, created_at) VALUES ($1, 'post', $2, 'like', $3)`, []int64{alice, carol}[i], post.ID, createdAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := &PostStore{db}
+	fq := PaginatedFeedQuery{Until: at.Add(time.Second).Format(time.RFC3339)}
+
+	candidates, err := store.GetFeedCandidates(ctx, alice, fq, 10, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(candidates) != 1 || candidates[0].ID != post.ID {
+		t.Fatalf("got %+v, expected the post of bob", candidates)
+	}
+	if c := candidates[0]; c.Comments != 1 || c.Reactions != 1 {
+		t.Errorf("got %d comments and %d reactions, expected those before the ranking only", c.Comments, c.Reactions)
+	}
+
+	affinities, err := store.Affinities(ctx, alice, []int64{bob, carol}, at.Add(-24*time.Hour), at.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := affinities[bob]; !got.Follows || got.Interactions != 2 {
+		t.Errorf("got %+v for bob, expected a follow and 2 interactions", got)
+	}
+	if got := affinities[carol]; got.Follows || got.Interactions != 0 {
+		t.Errorf("got %+v for carol, expected none", got)
+	}
+}
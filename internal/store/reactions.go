@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+// Things that can be reacted to.
+const (
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+)
+
+// ReactionKinds is the fixed set of reactions, each rendered as an emoji by
+// the clients.
+var ReactionKinds = []string{"like", "love", "laugh", "wow", "sad", "angry"}
+
+func IsReactionKind(kind string) bool {
+	return slices.Contains(ReactionKinds, kind)
+}
+
+// ReactionSummary aggregates the reactions on a post or comment. It is
+// embedded so the fields show up on the target itself.
+type ReactionSummary struct {
+	Reactions      map[string]int `json:"reactions"`
+	ReactedByMe    bool           `json:"reacted_by_me"`
+	ViewerReaction string         `json:"viewer_reaction,omitempty"`
+}
+
+type ReactionStore struct {
+	db *sql.DB
+}
+
+// Set records the user's reaction, replacing any other kind they had left on
+// the same target.
+func (store *ReactionStore) Set(ctx context.Context, userID int64, targetType string, targetID int64, kind string) error {
+	query := `
+		INSERT INTO reactions (user_id, target_type, target_id, kind)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, target_type, target_id) DO UPDATE
+		SET kind = EXCLUDED.kind, created_at = NOW()
+		WHERE reactions.kind <> EXCLUDED.kind
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, query, userID, targetType, targetID, kind)
+	return err
+}
+
+// Remove deletes the user's reaction if it is of the given kind.
+func (store *ReactionStore) Remove(ctx context.Context, userID int64, targetType string, targetID int64, kind string) error {
+	query := `
+		DELETE FROM reactions
+		WHERE user_id = $1 AND target_type = $2 AND target_id = $3 AND kind = $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, query, userID, targetType, targetID, kind)
+	return err
+}
+
+// Summaries loads the reaction counts of the targets, as seen by viewerID.
+// Every requested target has an entry.
+func (store *ReactionStore) Summaries(ctx context.Context, targetType string, targetIDs []int64, viewerID int64) (map[int64]*ReactionSummary, error) {
+	return reactionSummaries(ctx, store.db, targetType, targetIDs, viewerID)
+}
+
+func reactionSummaries(ctx context.Context, db *sql.DB, targetType string, targetIDs []int64, viewerID int64) (map[int64]*ReactionSummary, error) {
+	summaries := make(map[int64]*ReactionSummary, len(targetIDs))
+	for _, id := range targetIDs {
+		summaries[id] = &ReactionSummary{Reactions: map[string]int{}}
+	}
+
+	if len(targetIDs) == 0 {
+		return summaries, nil
+	}
+
+	query := `
+		SELECT target_id, kind, COUNT(*), BOOL_OR(user_id = $3)
+		FROM reactions
+		WHERE target_type = $1 AND target_id = ANY($2)
+		GROUP BY target_id, kind
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, targetType, pq.Array(targetIDs), viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			targetID int64
+			kind     string
+			count    int
+			mine     bool
+		)
+		if err := rows.Scan(&targetID, &kind, &count, &mine); err != nil {
+			return nil, err
+		}
+
+		summary := summaries[targetID]
+		summary.Reactions[kind] = count
+		if mine {
+			summary.ReactedByMe = true
+			summary.ViewerReaction = kind
+		}
+	}
+
+	return summaries, rows.Err()
+}
+
+// deletePostReactions removes the reactions on a post and on its comments.
+func deletePostReactions(ctx context.Context, tx *sql.Tx, postID int64) error {
+	query := `
+		DELETE FROM reactions
+		WHERE (target_type = $1 AND target_id = $3)
+		OR (target_type = $2 AND target_id IN (SELECT id FROM comments WHERE post_id = $3))
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, ReactionTargetPost, ReactionTargetComment, postID)
+	return err
+}
+
+// deleteCommentReactions removes the reactions on a comment and on the
+// replies below it.
+func deleteCommentReactions(ctx context.Context, tx *sql.Tx, commentID int64) error {
+	query := `
+		WITH RECURSIVE thread AS (
+			SELECT id FROM comments WHERE id = $2
+			UNION ALL
+			SELECT c.id FROM comments c JOIN thread t ON c.parent_id = t.id
+		)
+		DELETE FROM reactions
+		WHERE target_type = $1 AND target_id IN (SELECT id FROM thread)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, ReactionTargetComment, commentID)
+	return err
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestReactions(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := createTestPost(t, db, &Post{UserID: alice, Title: "post", Content: "post", Tags: []string{}}, day).ID
+
+	comment := &Comment{UserID: bob, PostID: post, Content: "comment"}
+	if err := (&CommentStore{db}).Create(ctx, comment); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &ReactionStore{db}
+
+	set := func(userID int64, targetType string, targetID int64, kind string) {
+		t.Helper()
+		if err := store.Set(ctx, userID, targetType, targetID, kind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	summary := func(targetType string, targetID, viewerID int64) *ReactionSummary {
+		t.Helper()
+		summaries, err := store.Summaries(ctx, targetType, []int64{targetID}, viewerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return summaries[targetID]
+	}
+
+	t.Run("one reaction per user and target", func(t *testing.T) {
+		set(alice, ReactionTargetPost, post, "like")
+		set(alice, ReactionTargetPost, post, "like")
+		set(bob, ReactionTargetPost, post, "like")
+		set(bob, ReactionTargetPost, post, "love")
+		// the same id as another kind of target is another target
+		set(bob, ReactionTargetComment, post, "wow")
+
+		got := summary(ReactionTargetPost, post, bob)
+		if want := map[string]int{"like": 1, "love": 1}; !maps.Equal(got.Reactions, want) {
+			t.Errorf("got reactions %v, expected %v", got.Reactions, want)
+		}
+		if !got.ReactedByMe || got.ViewerReaction != "love" {
+			t.Errorf("got reacted by me %t with %q, expected the love reaction", got.ReactedByMe, got.ViewerReaction)
+		}
+
+		if got := summary(ReactionTargetPost, post, createTestUser(t, db, "carol")); got.ReactedByMe {
+			t.Error("expected other viewers not to have reacted")
+		}
+	})
+
+	t.Run("removing another kind is a no-op", func(t *testing.T) {
+		if err := store.Remove(ctx, alice, ReactionTargetPost, post, "love"); err != nil {
+			t.Fatal(err)
+		}
+		if got := summary(ReactionTargetPost, post, alice); got.ViewerReaction != "like" {
+			t.Errorf("got reaction %q, expected like", got.ViewerReaction)
+		}
+
+		if err := store.Remove(ctx, alice, ReactionTargetPost, post, "like"); err != nil {
+			t.Fatal(err)
+		}
+		if got := summary(ReactionTargetPost, post, alice); got.ReactedByMe {
+			t.Error("expected the reaction to be removed")
+		}
+	})
+
+	t.Run("counts in the feed", func(t *testing.T) {
+		feed, err := (&PostStore{db}).GetUserFeed(ctx, alice, PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 20, Sort: "desc"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feed) != 1 {
+			t.Fatalf("got %d posts, expected 1", len(feed))
+		}
+
+		if want := map[string]int{"love": 1}; !maps.Equal(feed[0].Reactions, want) {
+			t.Errorf("got reactions %v, expected %v", feed[0].Reactions, want)
+		}
+	})
+
+	t.Run("deleting the post deletes the reactions", func(t *testing.T) {
+		set(alice, ReactionTargetComment, comment.ID, "laugh")
+
+		if err := (&PostStore{db}).Delete(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+
+		var left int
+		err := db.QueryRow(`
+			SELECT COUNT(*) FROM reactions
+			WHERE (target_type = $1 AND target_id = $2) OR (target_type = $3 AND target_id = $4)
+		`, ReactionTargetPost, post, ReactionTargetComment, comment.ID).Scan(&left)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if left != 0 {
+			t.Errorf("got %d reactions left on the deleted post and its comment", left)
+		}
+	})
+}
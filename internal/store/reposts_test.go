@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReposts(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	followers := &FollowerStore{db}
+	// alice follows bob and carol
+	for _, id := range []int64{bob, carol} {
+		if err := followers.Follow(ctx, alice, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &PostStore{db}
+
+	original := createTestPost(t, db, &Post{UserID: alice, Title: "original", Content: "original", Tags: []string{}}, day)
+
+	share := func(userID int64, of *Post, quote string, at time.Time) *Post {
+		t.Helper()
+		post := &Post{UserID: userID, Content: quote, Tags: []string{}}
+
+		var err error
+		if quote != "" {
+			err = store.Quote(ctx, post, of)
+		} else {
+			err = store.Repost(ctx, post, of)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Exec(`UPDATE posts SET created_at = $1 WHERE id = $2`, at, post.ID); err != nil {
+			t.Fatal(err)
+		}
+		return post
+	}
+
+	bobRepost := share(bob, original, "", day.Add(time.Hour))
+	quote := share(bob, original, "so true", day.Add(2*time.Hour))
+	// reposting a repost shares its original
+	carolRepost := share(carol, bobRepost, "", day.Add(3*time.Hour))
+
+	t.Run("reposts point at the original", func(t *testing.T) {
+		if *carolRepost.OriginalPostID != original.ID {
+			t.Errorf("got a repost of %d, expected %d", *carolRepost.OriginalPostID, original.ID)
+		}
+	})
+
+	t.Run("a post is reposted once per user", func(t *testing.T) {
+		err := store.Repost(ctx, &Post{UserID: bob, Tags: []string{}}, original)
+		if err != ErrorConflict {
+			t.Errorf("got %v, expected %v", err, ErrorConflict)
+		}
+
+		share(bob, original, "still true", day.Add(4*time.Hour))
+	})
+
+	t.Run("the feed shows an original once", func(t *testing.T) {
+		feed, err := store.GetUserFeed(ctx, alice, PaginatedFeedQuery{CursorQuery: CursorQuery{Limit: 20, Sort: "desc"}, Until: "2024-01-01T03:30:00Z"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []int64
+		for _, post := range feed {
+			got = append(got, post.ID)
+		}
+		if want := []int64{carolRepost.ID, quote.ID}; !slices.Equal(got, want) {
+			t.Fatalf("got posts %v, expected %v", got, want)
+		}
+
+		if feed[0].Original == nil || feed[0].Original.ID != original.ID || feed[0].Original.User.Username != "alice" {
+			t.Errorf("got original %+v, expected the post of alice", feed[0].Original)
+		}
+	})
+
+	t.Run("deleting the original tombstones quotes", func(t *testing.T) {
+		if err := store.Delete(ctx, original.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, repost := range []*Post{bobRepost, carolRepost} {
+			if _, err := store.GetByID(ctx, repost.ID); err != ErrorNotFound {
+				t.Errorf("got %v loading repost %d, expected %v", err, repost.ID, ErrorNotFound)
+			}
+		}
+
+		post, err := store.GetByID(ctx, quote.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AttachDetails(ctx, []*Post{post}, alice); err != nil {
+			t.Fatal(err)
+		}
+		if post.OriginalPostID != nil || post.Original != nil || !post.OriginalDeleted {
+			t.Errorf("got original %v, expected the quote to be marked as deleted", post.OriginalPostID)
+		}
+	})
+}
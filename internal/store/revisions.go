@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// PostRevision is a post as it was before one of its edits.
+type PostRevision struct {
+	ID        int64    `json:"id"`
+	PostID    int64    `json:"post_id"`
+	Version   int      `json:"version"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	EditedBy  *int64   `json:"edited_by"`
+	CreatedAt string   `json:"created_at"`
+	// Next is the post as the edit left it, either the following revision
+	// or the current post
+	Next PostSnapshot `json:"-"`
+}
+
+type PostSnapshot struct {
+	Title   string
+	Content string
+	Tags    []string
+}
+
+type RevisionStore struct {
+	db *sql.DB
+}
+
+// List returns a page of the revisions of a post.
+func (store *RevisionStore) List(ctx context.Context, postID int64, cq CursorQuery) ([]PostRevision, error) {
+	keyset, page, args := cq.keyset("created_at", "id", 2)
+
+	query := `
+		SELECT id, post_id, version, title, content, tags, edited_by, created_at,
+			next_title, next_content, next_tags
+		FROM (
+			SELECT r.id, r.post_id, r.version, r.title, r.content, r.tags, r.edited_by, r.created_at,
+				CASE WHEN ROW_NUMBER() OVER w = 1 THEN p.title ELSE LAG(r.title) OVER w END AS next_title,
+				CASE WHEN ROW_NUMBER() OVER w = 1 THEN p.content ELSE LAG(r.content) OVER w END AS next_content,
+				CASE WHEN ROW_NUMBER() OVER w = 1 THEN p.tags ELSE LAG(r.tags) OVER w END AS next_tags
+			FROM post_revisions r
+			JOIN posts p ON p.id = r.post_id
+			WHERE r.post_id = $1
+			WINDOW w AS (ORDER BY r.version DESC)
+		) revisions
+		WHERE ` + keyset + page
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, append([]any{postID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var r PostRevision
+		err := rows.Scan(
+			&r.ID,
+			&r.PostID,
+			&r.Version,
+			&r.Title,
+			&r.Content,
+			pq.Array(&r.Tags),
+			&r.EditedBy,
+			&r.CreatedAt,
+			&r.Next.Title,
+			&r.Next.Content,
+			pq.Array(&r.Next.Tags),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (store *RevisionStore) GetByVersion(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	query := `
+		SELECT id, post_id, version, title, content, tags, edited_by, created_at
+		FROM post_revisions
+		WHERE post_id = $1 AND version = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var r PostRevision
+	err := store.db.QueryRowContext(ctx, query, postID, version).Scan(
+		&r.ID,
+		&r.PostID,
+		&r.Version,
+		&r.Title,
+		&r.Content,
+		pq.Array(&r.Tags),
+		&r.EditedBy,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &r, nil
+}
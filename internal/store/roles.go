@@ -3,22 +3,57 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+// Permissions a role can be granted.
+const (
+	PermissionPostUpdateAny    = "post.update.any"
+	PermissionPostDeleteAny    = "post.delete.any"
+	PermissionPostRestore      = "post.restore"
+	PermissionCommentUpdateAny = "comment.update.any"
+	PermissionCommentDeleteAny = "comment.delete.any"
+	PermissionUserBan          = "user.ban"
+	PermissionRoleCreate       = "role.create"
+	PermissionRoleAssign       = "role.assign"
+	PermissionFeedDebug        = "feed.debug"
 )
 
+var ErrorUnknownPermission = errors.New("unknown permission")
+
 type Role struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Level       int64  `json:"level"`
+	ID                int64    `json:"id"`
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Level             int64    `json:"level"`
+	RequiresTwoFactor bool     `json:"requires_two_factor"`
+	Permissions       []string `json:"permissions"`
+}
+
+func (r *Role) HasPermission(permission string) bool {
+	return slices.Contains(r.Permissions, permission)
 }
 
 type RoleStore struct {
 	db *sql.DB
 }
 
+// rolePermissionsColumn selects the permission names of the role in `roles`.
+const rolePermissionsColumn = `
+	ARRAY(
+		SELECT p.name FROM role_permissions rp
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE rp.role_id = roles.id
+		ORDER BY p.name
+	)
+`
+
 func (store *RoleStore) GetByName(ctx context.Context, roleName string) (*Role, error) {
 	query := `
-	SELECT id, name, description, level FROM roles
+	SELECT id, name, COALESCE(description, ''), level, requires_two_factor, ` + rolePermissionsColumn + ` FROM roles
 	WHERE name = $1
 	`
 	role := &Role{}
@@ -31,11 +66,104 @@ func (store *RoleStore) GetByName(ctx context.Context, roleName string) (*Role,
 		&role.Name,
 		&role.Description,
 		&role.Level,
+		&role.RequiresTwoFactor,
+		pq.Array(&role.Permissions),
 	)
 
 	if err != nil {
-		return nil, err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
 	}
 
 	return role, nil
 }
+
+func (store *RoleStore) GetAll(ctx context.Context) ([]Role, error) {
+	query := `
+		SELECT id, name, COALESCE(description, ''), level, requires_two_factor, ` + rolePermissionsColumn + ` FROM roles
+		ORDER BY id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+		err := rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.Level,
+			&role.RequiresTwoFactor,
+			pq.Array(&role.Permissions),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// Create adds a role together with its permissions.
+func (store *RoleStore) Create(ctx context.Context, role *Role) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO roles (name, description, requires_two_factor)
+			VALUES ($1, $2, $3) RETURNING id, level
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(ctx, query, role.Name, role.Description, role.RequiresTwoFactor).Scan(
+			&role.ID,
+			&role.Level,
+		)
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "roles_name_key"`:
+				return ErrorConflict
+			default:
+				return err
+			}
+		}
+
+		if len(role.Permissions) == 0 {
+			return nil
+		}
+
+		query = `
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT $1, id FROM permissions WHERE name = ANY($2)
+		`
+		res, err := tx.ExecContext(ctx, query, role.ID, pq.Array(role.Permissions))
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows != int64(len(role.Permissions)) {
+			return ErrorUnknownPermission
+		}
+
+		return nil
+	})
+}
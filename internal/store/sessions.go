@@ -0,0 +1,277 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrorTokenReused = errors.New("refresh token has already been used")
+
+type Session struct {
+	ID         string  `json:"id"`
+	UserID     int64   `json:"user_id"`
+	UserAgent  string  `json:"user_agent"`
+	IP         string  `json:"ip"`
+	CreatedAt  string  `json:"created_at"`
+	LastSeenAt string  `json:"last_seen_at"`
+	RevokedAt  *string `json:"revoked_at,omitempty"`
+	Current    bool    `json:"current"`
+}
+
+type SessionStore struct {
+	db *sql.DB
+}
+
+func (store *SessionStore) Create(ctx context.Context, session *Session, refreshToken string, exp time.Duration) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		// create the session
+		if err := store.create(ctx, tx, session); err != nil {
+			return err
+		}
+
+		// create the first refresh token of the session
+		if err := store.createRefreshToken(ctx, tx, session, refreshToken, exp); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (store *SessionStore) create(ctx context.Context, tx *sql.Tx, session *Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip)
+		VALUES ($1, $2, $3, $4) RETURNING created_at, last_seen_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return tx.QueryRowContext(ctx, query,
+		session.ID,
+		session.UserID,
+		session.UserAgent,
+		session.IP,
+	).Scan(
+		&session.CreatedAt,
+		&session.LastSeenAt,
+	)
+}
+
+func (store *SessionStore) createRefreshToken(ctx context.Context, tx *sql.Tx, session *Session, token string, exp time.Duration) error {
+	query := `
+		INSERT INTO refresh_tokens (token, session_id, user_id, expiry)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, hashToken(token), session.ID, session.UserID, time.Now().Add(exp))
+	return err
+}
+
+func (store *SessionStore) GetByID(ctx context.Context, id string) (*Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip, created_at, last_seen_at, revoked_at FROM sessions
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	session := &Session{}
+	err := store.db.QueryRowContext(ctx, query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// GetByUserID lists the sessions a user is still signed in with: those not
+// revoked whose latest refresh token has not expired.
+func (store *SessionStore) GetByUserID(ctx context.Context, userID int64) ([]Session, error) {
+	query := `
+		SELECT s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_seen_at FROM sessions s
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL AND EXISTS (
+			SELECT 1 FROM refresh_tokens rt
+			WHERE rt.session_id = s.id AND rt.used_at IS NULL AND rt.expiry > NOW()
+		)
+		ORDER BY s.last_seen_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var s Session
+		err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// Touch records activity on a session. Writes are throttled to one a minute
+// since it runs on every authenticated request.
+func (store *SessionStore) Touch(ctx context.Context, id, ip string) error {
+	query := `
+		UPDATE sessions SET last_seen_at = NOW(), ip = $2
+		WHERE id = $1 AND last_seen_at < NOW() - INTERVAL '1 minute'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, query, id, ip)
+	return err
+}
+
+// Delete removes one of the user's sessions along with its refresh tokens.
+func (store *SessionStore) Delete(ctx context.Context, userID int64, id string) error {
+	query := `DELETE FROM sessions WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := store.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// Refresh exchanges a refresh token for a new one in the same session. Every
+// refresh token can be used once: presenting a token that was already rotated
+// revokes the whole session, since either the client or an attacker holds a
+// stolen copy.
+func (store *SessionStore) Refresh(ctx context.Context, token, newToken string, exp time.Duration) (*Session, error) {
+	var (
+		session *Session
+		reused  bool
+	)
+
+	err := WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_seen_at, s.revoked_at,
+				rt.expiry, rt.used_at IS NOT NULL
+			FROM refresh_tokens rt
+			JOIN sessions s ON s.id = rt.session_id
+			WHERE rt.token = $1
+			FOR UPDATE
+		`
+
+		var (
+			expiry time.Time
+			used   bool
+		)
+
+		s := &Session{}
+		err := tx.QueryRowContext(ctx, query, hashToken(token)).Scan(
+			&s.ID,
+			&s.UserID,
+			&s.UserAgent,
+			&s.IP,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+			&s.RevokedAt,
+			&expiry,
+			&used,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+
+		if s.RevokedAt != nil {
+			return ErrorNotFound
+		}
+
+		// reuse detection: the whole token family goes down with it
+		if used {
+			reused = true
+			return store.revoke(ctx, tx, s.ID)
+		}
+
+		if time.Now().After(expiry) {
+			return ErrorNotFound
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE token = $1`, hashToken(token)); err != nil {
+			return err
+		}
+
+		if err := store.createRefreshToken(ctx, tx, s, newToken, exp); err != nil {
+			return err
+		}
+
+		session = s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reused {
+		return nil, ErrorTokenReused
+	}
+
+	return session, nil
+}
+
+func (store *SessionStore) Revoke(ctx context.Context, id string) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		return store.revoke(ctx, tx, id)
+	})
+}
+
+func (store *SessionStore) revoke(ctx context.Context, tx *sql.Tx, id string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionsByUserID(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	store := &SessionStore{db}
+	create := func(id string, userID int64, exp time.Duration) {
+		t.Helper()
+		session := &Session{ID: id, UserID: userID, UserAgent: "test", IP: "127.0.0.1"}
+		if err := store.Create(ctx, session, id, exp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	create("00000000-0000-0000-0000-000000000001", alice, time.Hour)
+	create("00000000-0000-0000-0000-000000000002", alice, -time.Hour)
+	create("00000000-0000-0000-0000-000000000003", alice, time.Hour)
+	create("00000000-0000-0000-0000-000000000004", bob, time.Hour)
+
+	if err := store.Revoke(ctx, "00000000-0000-0000-0000-000000000003"); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := store.GetByUserID(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "00000000-0000-0000-0000-000000000001" {
+		t.Errorf("got %+v, expected only the live session", sessions)
+	}
+
+	// rotating the refresh token keeps the session listed
+	if _, err := store.Refresh(ctx, "00000000-0000-0000-0000-000000000001", "rotated", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, err = store.GetByUserID(ctx, alice); err != nil || len(sessions) != 1 {
+		t.Errorf("got %d sessions and %v after a refresh, expected 1", len(sessions), err)
+	}
+
+	// users cannot delete the sessions of others
+	if err := store.Delete(ctx, alice, "00000000-0000-0000-0000-000000000004"); !errors.Is(err, ErrorNotFound) {
+		t.Errorf("got %v, expected %v", err, ErrorNotFound)
+	}
+
+	if err := store.Delete(ctx, alice, "00000000-0000-0000-0000-000000000001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Refresh(ctx, "rotated", "again", time.Hour); !errors.Is(err, ErrorNotFound) {
+		t.Errorf("got %v refreshing a deleted session, expected %v", err, ErrorNotFound)
+	}
+}
@@ -2,7 +2,9 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"time"
 )
@@ -10,6 +12,7 @@ import (
 var (
 	ErrorNotFound        = errors.New("resource not found")
 	ErrorConflict        = errors.New("resource already exists")
+	ErrorEditConflict    = errors.New("resource was modified concurrently")
 	QueryTimeoutDuration = time.Second * 5
 )
 
@@ -17,9 +20,22 @@ type Storage struct {
 	Posts interface {
 		Create(context.Context, *Post) error
 		GetByID(context.Context, int64) (*Post, error)
-		Update(context.Context, *Post) error
+		Update(context.Context, *Post, int64) error
 		Delete(context.Context, int64) error
 		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		Repost(context.Context, *Post, *Post) error
+		Quote(context.Context, *Post, *Post) error
+		DeleteRepost(context.Context, int64, *Post) error
+		AttachDetails(context.Context, []*Post, int64) error
+		ListUnpublished(context.Context, int64, CursorQuery) ([]Post, error)
+		PublishDue(context.Context, int) ([]int64, error)
+		ListByHashtag(context.Context, string, int64, CursorQuery) ([]PostWithMetadata, error)
+		ListMentioning(context.Context, int64, CursorQuery) ([]PostWithMetadata, error)
+		GetTimelineFeed(context.Context, int64, []int64, int, *time.Time, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		TimelineEntries(context.Context, int64, int) ([]TimelineEntry, error)
+		AuthorEntries(context.Context, int64, int) ([]TimelineEntry, error)
+		GetFeedCandidates(context.Context, int64, PaginatedFeedQuery, int, time.Time) ([]FeedCandidate, error)
+		Affinities(context.Context, int64, []int64, time.Time, time.Time) (map[int64]Affinity, error)
 	}
 	Users interface {
 		Create(context.Context, *sql.Tx, *User) error
@@ -28,29 +44,110 @@ type Storage struct {
 		Activate(context.Context, string) error
 		Delete(context.Context, int64) error
 		GetByEmail(context.Context, string) (*User, error)
+		CreatePasswordReset(context.Context, int64, string, time.Duration) error
+		ResetPassword(context.Context, string, string) error
+		ReplaceInvitation(context.Context, int64, string, time.Duration) error
+		PurgeInactive(context.Context, time.Duration) (int64, int64, error)
+		SetRole(context.Context, int64, string) error
 	}
 
 	Comments interface {
-		GetByPostID(context.Context, int64) ([]Comment, error)
+		List(context.Context, int64, *int64, CursorQuery) ([]Comment, error)
+		GetByID(context.Context, int64) (*Comment, error)
 		Create(context.Context, *Comment) error
+		Update(context.Context, *Comment) error
+		Delete(context.Context, int64) error
 	}
 
 	Followers interface {
 		Follow(ctx context.Context, followerID, UserID int64) error
 		Unfollow(ctx context.Context, followerID, UserID int64) error
+		IsFollowing(ctx context.Context, followerID, UserID int64) (bool, error)
+		ListFollowers(context.Context, int64, CursorQuery) ([]FollowEntry, error)
+		ListFollowing(context.Context, int64, CursorQuery) ([]FollowEntry, error)
+		ListFollowerIDs(context.Context, int64, int) ([]int64, error)
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
+		GetAll(context.Context) ([]Role, error)
+		Create(context.Context, *Role) error
+	}
+	Sessions interface {
+		Create(context.Context, *Session, string, time.Duration) error
+		GetByID(context.Context, string) (*Session, error)
+		GetByUserID(context.Context, int64) ([]Session, error)
+		Refresh(context.Context, string, string, time.Duration) (*Session, error)
+		Touch(context.Context, string, string) error
+		Revoke(context.Context, string) error
+		Delete(context.Context, int64, string) error
+	}
+	APIKeys interface {
+		Create(context.Context, *APIKey, string) error
+		GetByUserID(context.Context, int64) ([]APIKey, error)
+		GetByKey(context.Context, string) (*APIKey, error)
+		Touch(context.Context, int64) error
+		Revoke(context.Context, int64, int64) error
+	}
+	TwoFactor interface {
+		GetByUserID(context.Context, int64) (*TwoFactor, error)
+		SetSecret(context.Context, int64, string) error
+		Enable(context.Context, int64, int64, []string) error
+		Disable(context.Context, int64) error
+		UseStep(context.Context, int64, int64) error
+		UseRecoveryCode(context.Context, int64, string) error
+	}
+	Reactions interface {
+		Set(context.Context, int64, string, int64, string) error
+		Remove(context.Context, int64, string, int64, string) error
+		Summaries(context.Context, string, []int64, int64) (map[int64]*ReactionSummary, error)
+	}
+	Bookmarks interface {
+		CreateCollection(context.Context, *BookmarkCollection) error
+		GetCollections(context.Context, int64) ([]BookmarkCollection, error)
+		DeleteCollection(context.Context, int64, int64) error
+		Add(context.Context, int64, int64, int64) error
+		Remove(context.Context, int64, int64, int64) error
+		ListPosts(context.Context, int64, int64, CursorQuery) ([]BookmarkedPost, error)
+	}
+	Attachments interface {
+		Create(context.Context, *Attachment, int) error
+		Delete(context.Context, int64, int64) (*Attachment, error)
+		Keys(context.Context, int64) ([]string, error)
+		GetPost(context.Context, string) (*Post, error)
+	}
+	Polls interface {
+		Vote(context.Context, int64, int64, []int64) error
+		Get(context.Context, int64, int64) (*Poll, error)
+	}
+	Revisions interface {
+		List(context.Context, int64, CursorQuery) ([]PostRevision, error)
+		GetByVersion(context.Context, int64, int) (*PostRevision, error)
+	}
+	Identities interface {
+		CreateState(context.Context, *OAuthState, time.Duration) error
+		ConsumeState(context.Context, string, string) (*OAuthState, error)
+		GetUserID(context.Context, string, string) (int64, error)
+		Link(context.Context, *User, *Identity) error
+		CreateUser(context.Context, *User, *Identity) error
 	}
 }
 
 func NewStore(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostStore{db},
-		Users:     &UserStore{db},
-		Comments:  &CommentStore{db},
-		Followers: &FollowerStore{db},
-		Roles:     &RoleStore{db},
+		Posts:       &PostStore{db},
+		Users:       &UserStore{db},
+		Comments:    &CommentStore{db},
+		Followers:   &FollowerStore{db},
+		Roles:       &RoleStore{db},
+		Sessions:    &SessionStore{db},
+		APIKeys:     &APIKeyStore{db},
+		TwoFactor:   &TwoFactorStore{db},
+		Identities:  &IdentityStore{db},
+		Reactions:   &ReactionStore{db},
+		Bookmarks:   &BookmarkStore{db},
+		Revisions:   &RevisionStore{db},
+		Attachments: &AttachmentStore{db},
+		Polls:       &PollStore{db},
 	}
 }
 
@@ -66,3 +163,10 @@ func WithTx(db *sql.DB, ctx context.Context, fn func(*sql.Tx) error) error {
 	}
 	return tx.Commit()
 }
+
+// hashToken returns the hex encoded sha256 of an opaque token, which is what
+// gets persisted so a database leak does not expose usable tokens.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TimelineEntry is a post in a home timeline, UserID being its author.
+type TimelineEntry struct {
+	PostID    int64
+	UserID    int64
+	CreatedAt time.Time
+}
+
+// GetTimelineFeed is GetUserFeed limited to the posts of a cached timeline and
+// to the posts of the followed users with more than maxFollowers followers,
+// which are not pushed to timelines. When after is set only posts created
+// after it are returned.
+func (store *PostStore) GetTimelineFeed(ctx context.Context, id int64, postIDs []int64, maxFollowers int, after *time.Time, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return store.feed(ctx, id, fq, `(p.id = ANY($9) OR p.user_id IN (
+		SELECT f.user_id FROM followers f
+		WHERE f.follower_id = $1 AND (SELECT COUNT(*) FROM followers c WHERE c.user_id = f.user_id) > $10
+	)) AND ($11::timestamptz IS NULL OR p.created_at > $11)`, pq.Array(postIDs), maxFollowers, after)
+}
+
+// TimelineEntries returns the newest published posts of the user and of the
+// users they follow, to build their timeline from.
+func (store *PostStore) TimelineEntries(ctx context.Context, userID int64, limit int) ([]TimelineEntry, error) {
+	query := `
+		SELECT p.id, p.user_id, p.created_at
+		FROM posts p
+		WHERE p.status = 'published' AND (p.user_id = $1 OR p.user_id IN (
+			SELECT f.user_id FROM followers f WHERE f.follower_id = $1
+		))
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $2
+	`
+
+	return store.timelineEntries(ctx, query, userID, limit)
+}
+
+// AuthorEntries returns the newest published posts of a user, to add to the
+// timelines of their new followers.
+func (store *PostStore) AuthorEntries(ctx context.Context, userID int64, limit int) ([]TimelineEntry, error) {
+	query := `
+		SELECT p.id, p.user_id, p.created_at
+		FROM posts p
+		WHERE p.status = 'published' AND p.user_id = $1
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $2
+	`
+
+	return store.timelineEntries(ctx, query, userID, limit)
+}
+
+func (store *PostStore) timelineEntries(ctx context.Context, query string, args ...any) ([]TimelineEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []TimelineEntry{}
+	for rows.Next() {
+		var entry TimelineEntry
+		if err := rows.Scan(&entry.PostID, &entry.UserID, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrorInvalidCode = errors.New("invalid two-factor code")
+
+type TwoFactor struct {
+	UserID   int64
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
+
+type TwoFactorStore struct {
+	db *sql.DB
+}
+
+func (store *TwoFactorStore) GetByUserID(ctx context.Context, userID int64) (*TwoFactor, error) {
+	query := `
+		SELECT id, COALESCE(totp_secret, ''), totp_enabled, COALESCE(totp_last_step, 0) FROM users
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tf := &TwoFactor{}
+	err := store.db.QueryRowContext(ctx, query, userID).Scan(&tf.UserID, &tf.Secret, &tf.Enabled, &tf.LastStep)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return tf, nil
+}
+
+// SetSecret stores a pending secret. It is not used for logins until Enable
+// is called with a code proving the user's authenticator has it.
+func (store *TwoFactorStore) SetSecret(ctx context.Context, userID int64, secret string) error {
+	query := `UPDATE users SET totp_secret = $1 WHERE id = $2 AND totp_enabled = FALSE`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := store.db.ExecContext(ctx, query, secret, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorConflict
+	}
+
+	return nil
+}
+
+func (store *TwoFactorStore) Enable(ctx context.Context, userID, step int64, recoveryCodes []string) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE users SET totp_enabled = TRUE, totp_last_step = $1
+			WHERE id = $2 AND totp_enabled = FALSE AND totp_secret IS NOT NULL
+		`
+
+		res, err := tx.ExecContext(ctx, query, step, userID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrorConflict
+		}
+
+		return store.replaceRecoveryCodes(ctx, tx, userID, recoveryCodes)
+	})
+}
+
+func (store *TwoFactorStore) Disable(ctx context.Context, userID int64) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = NULL
+			WHERE id = $1
+		`
+
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+
+		return store.replaceRecoveryCodes(ctx, tx, userID, nil)
+	})
+}
+
+// UseStep records the time step of an accepted code. A step can only be used
+// once, so a code that was observed cannot be replayed.
+func (store *TwoFactorStore) UseStep(ctx context.Context, userID, step int64) error {
+	query := `
+		UPDATE users SET totp_last_step = $1
+		WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := store.db.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorInvalidCode
+	}
+
+	return nil
+}
+
+func (store *TwoFactorStore) UseRecoveryCode(ctx context.Context, userID int64, code string) error {
+	query := `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE code = $1 AND user_id = $2 AND used_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := store.db.ExecContext(ctx, query, hashToken(code), userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorInvalidCode
+	}
+
+	return nil
+}
+
+func (store *TwoFactorStore) replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64, codes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		query := `INSERT INTO recovery_codes (code, user_id) VALUES ($1, $2)`
+		if _, err := tx.ExecContext(ctx, query, hashToken(code), userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,29 +2,35 @@ package store
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
-	"encoding/hex"
 	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrorDuplicateEmail    = errors.New("a user with that email already exists")
-	ErrorDuplicateUsername = errors.New("a user with that username already exists")
+	ErrorDuplicateEmail      = errors.New("a user with that email already exists")
+	ErrorDuplicateUsername   = errors.New("a user with that username already exists")
+	ErrorInvalidCredentials  = errors.New("invalid email or password")
+	ErrorAccountNotActivated = errors.New("account has not been activated")
 )
 
+// dummyHash is compared against when a user has no stored hash (e.g. the email
+// is unknown) so a failed login costs the same bcrypt work either way.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("social-dummy-password"), bcrypt.DefaultCost)
+
 type User struct {
-	ID        int64    `json:"id"`
-	Username  string   `json:"username"`
-	Email     string   `json:"email"`
-	Password  password `json:"-"`
-	CreatedAt string   `json:"created_at"`
-	IsActive  bool     `json:"is_active"`
-	RoleID    int64    `json:"role_id"`
-	Role      Role     `json:"role"`
+	ID               int64    `json:"id"`
+	Username         string   `json:"username"`
+	Email            string   `json:"email"`
+	Password         password `json:"-"`
+	CreatedAt        string   `json:"created_at"`
+	IsActive         bool     `json:"is_active"`
+	TwoFactorEnabled bool     `json:"two_factor_enabled"`
+	RoleID           int64    `json:"role_id"`
+	Role             Role     `json:"role"`
 }
 
 type password struct {
@@ -44,6 +50,29 @@ func (p *password) Set(text string) error {
 	return nil
 }
 
+func (p *password) Compare(text string) error {
+	hash := p.hash
+	if len(hash) == 0 {
+		hash = dummyHash
+	}
+
+	err := bcrypt.CompareHashAndPassword(hash, []byte(text))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return ErrorInvalidCredentials
+		default:
+			return err
+		}
+	}
+
+	if len(p.hash) == 0 {
+		return ErrorInvalidCredentials
+	}
+
+	return nil
+}
+
 type UserStore struct {
 	db *sql.DB
 }
@@ -89,7 +118,10 @@ func (store *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) erro
 func (store *UserStore) GetByID(ctx context.Context, userId int64) (*User, error) {
 	var user User
 	query := `
-		SELECT users.id, username, email, created_at, roles.* FROM users
+		SELECT users.id, username, email, created_at, is_active, totp_enabled, role_id,
+			roles.id, roles.name, roles.level, roles.description, roles.requires_two_factor,
+			` + rolePermissionsColumn + `
+		FROM users
 		JOIN roles ON (users.role_id = roles.id)
 		WHERE users.id = $1
 	`
@@ -98,10 +130,15 @@ func (store *UserStore) GetByID(ctx context.Context, userId int64) (*User, error
 	defer cancel()
 
 	err := store.db.QueryRowContext(ctx, query, userId).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt,
+		&user.IsActive,
+		&user.TwoFactorEnabled,
+		&user.RoleID,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Level,
 		&user.Role.Description,
+		&user.Role.RequiresTwoFactor,
+		pq.Array(&user.Role.Permissions),
 	)
 	if err != nil {
 		switch {
@@ -131,14 +168,15 @@ func (store *UserStore) CreateAndInvite(ctx context.Context, user *User, token s
 	})
 }
 
+// createUserInvitation stores the hash of the token, which is only ever sent
+// to the user.
 func (store *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, exp time.Duration, userID int64) error {
-
 	query := `INSERT INTO user_invitations(token, user_id, expiry) VALUES($1, $2, $3)`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := tx.ExecContext(ctx, query, token, userID, time.Now().Add(exp))
+	_, err := tx.ExecContext(ctx, query, hashToken(token), userID, time.Now().Add(exp))
 	if err != nil {
 		return err
 	}
@@ -146,6 +184,49 @@ func (store *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, to
 	return nil
 }
 
+// ReplaceInvitation invalidates any pending invitation of the user and issues
+// a new one.
+func (store *UserStore) ReplaceInvitation(ctx context.Context, userID int64, token string, invitationExp time.Duration) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		if err := store.deleteUserInvitation(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		return store.createUserInvitation(ctx, tx, token, invitationExp, userID)
+	})
+}
+
+// PurgeInactive removes expired invitations and accounts that were never
+// activated within the grace period and have no pending invitation left.
+func (store *UserStore) PurgeInactive(ctx context.Context, grace time.Duration) (invitations int64, users int64, err error) {
+	err = WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM user_invitations WHERE expiry <= $1`, time.Now())
+		if err != nil {
+			return err
+		}
+		if invitations, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		query := `
+			DELETE FROM users u
+			WHERE u.is_active = FALSE AND u.created_at < $1
+			AND NOT EXISTS (SELECT 1 FROM user_invitations ui WHERE ui.user_id = u.id)
+		`
+		res, err = tx.ExecContext(ctx, query, time.Now().Add(-grace))
+		if err != nil {
+			return err
+		}
+		users, err = res.RowsAffected()
+		return err
+	})
+
+	return invitations, users, err
+}
+
 func (store *UserStore) Activate(ctx context.Context, token string) error {
 
 	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
@@ -176,14 +257,11 @@ func (store *UserStore) getUserFromInvitation(ctx context.Context, tx *sql.Tx, t
 		WHERE ui.token = $1 AND ui.expiry > $2
 	`
 
-	hash := sha256.Sum256([]byte(token))
-	hashToken := hex.EncodeToString(hash[:])
-
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	user := &User{}
-	err := tx.QueryRowContext(ctx, query, hashToken, time.Now()).Scan(
+	err := tx.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -256,16 +334,49 @@ func (store *UserStore) delete(ctx context.Context, tx *sql.Tx, id int64) error
 	return nil
 }
 
+// SetRole changes the role of a user.
+func (store *UserStore) SetRole(ctx context.Context, userID int64, roleName string) error {
+	query := `UPDATE users SET role_id = (SELECT id FROM roles WHERE name = $1) WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := store.db.ExecContext(ctx, query, roleName, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
 func (store *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	var user User
 	query := `
-		SELECT id, email, username, created_at FROM users WHERE email = $1;
+		SELECT id, email, username, password, created_at, is_active, totp_enabled, role_id FROM users WHERE email = $1;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := store.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Email, &user.Username, &user.CreatedAt)
+	err := store.db.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.Password.hash,
+		&user.CreatedAt,
+		&user.IsActive,
+		&user.TwoFactorEnabled,
+		&user.RoleID,
+	)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -277,3 +388,108 @@ func (store *UserStore) GetByEmail(ctx context.Context, email string) (*User, er
 
 	return &user, nil
 }
+
+func (store *UserStore) CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		// only the latest reset link stays valid
+		if err := store.deletePasswordResets(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO password_resets(token, user_id, expiry) VALUES($1, $2, $3)`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		_, err := tx.ExecContext(ctx, query, hashToken(token), userID, time.Now().Add(exp))
+		return err
+	})
+}
+
+func (store *UserStore) ResetPassword(ctx context.Context, token, newPassword string) error {
+	return WithTx(store.db, ctx, func(tx *sql.Tx) error {
+		// find the user that this token belongs to
+		user, err := store.getUserFromPasswordReset(ctx, tx, token)
+		if err != nil {
+			return err
+		}
+
+		// set the new password
+		if err := user.Password.Set(newPassword); err != nil {
+			return err
+		}
+		if err := store.updatePassword(ctx, tx, user); err != nil {
+			return err
+		}
+
+		// the token is single use
+		if err := store.deletePasswordResets(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		// sign the user out everywhere
+		if err := store.revokeSessions(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (store *UserStore) getUserFromPasswordReset(ctx context.Context, tx *sql.Tx, token string) (*User, error) {
+	query := `
+		SELECT u.id, u.username, u.email, u.created_at, u.is_active FROM users u
+		JOIN password_resets pr ON u.id = pr.user_id
+		WHERE pr.token = $1 AND pr.expiry > $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	user := &User{}
+	err := tx.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.CreatedAt,
+		&user.IsActive,
+	)
+
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (store *UserStore) updatePassword(ctx context.Context, tx *sql.Tx, user *User) error {
+	query := `UPDATE users SET password = $1 WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, user.Password.hash, user.ID)
+	return err
+}
+
+func (store *UserStore) deletePasswordResets(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `DELETE FROM password_resets WHERE user_id = $1`
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (store *UserStore) revokeSessions(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
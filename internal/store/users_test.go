@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResetPassword(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	users := &UserStore{db}
+	sessions := &SessionStore{db}
+
+	session := &Session{ID: "00000000-0000-0000-0000-000000000001", UserID: alice, UserAgent: "test", IP: "127.0.0.1"}
+	if err := sessions.Create(ctx, session, "refresh", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("expired tokens", func(t *testing.T) {
+		if err := users.CreatePasswordReset(ctx, bob, "expired", -time.Hour); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := users.ResetPassword(ctx, "expired", "secret123"); !errors.Is(err, ErrorNotFound) {
+			t.Errorf("got %v, expected %v", err, ErrorNotFound)
+		}
+	})
+
+	t.Run("only the latest token", func(t *testing.T) {
+		if err := users.CreatePasswordReset(ctx, alice, "first", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if err := users.CreatePasswordReset(ctx, alice, "second", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := users.ResetPassword(ctx, "first", "secret123"); !errors.Is(err, ErrorNotFound) {
+			t.Errorf("got %v, expected %v", err, ErrorNotFound)
+		}
+	})
+
+	t.Run("resetting the password", func(t *testing.T) {
+		if err := users.ResetPassword(ctx, "second", "secret123"); err != nil {
+			t.Fatal(err)
+		}
+
+		user, err := users.GetByEmail(ctx, "alice@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := user.Password.Compare("secret123"); err != nil {
+			t.Errorf("got %v comparing the new password", err)
+		}
+
+		// every session is signed out
+		s, err := sessions.GetByID(ctx, session.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.RevokedAt == nil {
+			t.Error("expected the session to be revoked")
+		}
+
+		// the token is single use
+		if err := users.ResetPassword(ctx, "second", "secret456"); !errors.Is(err, ErrorNotFound) {
+			t.Errorf("got %v reusing the token, expected %v", err, ErrorNotFound)
+		}
+	})
+}